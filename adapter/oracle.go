@@ -1,10 +1,8 @@
 package adapter
 
 import (
-	"fmt"
-
 	"github.com/cdleo/go-commons/sqlcommons"
-	"github.com/godror/godror"
+	"github.com/cdleo/go-sqldb/sqlerrors"
 )
 
 type oracleAdapter struct{}
@@ -22,8 +20,8 @@ func (s *oracleAdapter) ErrorHandler(err error) error {
 		return nil
 	}
 
-	if oraError, ok := godror.AsOraErr(err); ok {
-		switch oraError.Code() {
+	if code, message, ok := oraError(err); ok {
+		switch code {
 		case 1: //ORA-00001"
 			return sqlcommons.UniqueConstraintViolation
 		case 2291, 2292: //ORA-02291 (PKNotFound) AND ORA-02292 (ChildFound)
@@ -38,8 +36,10 @@ func (s *oracleAdapter) ErrorHandler(err error) error {
 			return sqlcommons.InvalidNumericValue
 		case 1427: //ORA-01427
 			return sqlcommons.SubqueryReturnsMoreThanOneRow
+		case 1013, 3156: //ORA-01013 (user requested cancel) AND ORA-03156 (call timed out)
+			return sqlerrors.QueryTimeout
 		default:
-			return fmt.Errorf("Unhandled Oracle error. Code:[%d] Desc:[%s]", oraError.Code(), oraError.Message())
+			return newUnhandledError(err, "Unhandled Oracle error. Code:[%d] Desc:[%s]", code, message)
 		}
 	} else {
 		return err
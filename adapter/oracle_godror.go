@@ -0,0 +1,14 @@
+//go:build cgo
+
+package adapter
+
+import (
+	"github.com/godror/godror"
+)
+
+func oraError(err error) (int, string, bool) {
+	if oraError, ok := godror.AsOraErr(err); ok {
+		return oraError.Code(), oraError.Message(), true
+	}
+	return 0, "", false
+}
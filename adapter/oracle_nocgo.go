@@ -0,0 +1,8 @@
+//go:build !cgo
+
+package adapter
+
+// Without cgo godror is left out, so there are no Oracle errors to map.
+func oraError(err error) (int, string, bool) {
+	return 0, "", false
+}
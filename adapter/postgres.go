@@ -1,11 +1,13 @@
 package adapter
 
 import (
-	"fmt"
+	"errors"
 	"regexp"
 	"strings"
 
 	"github.com/cdleo/go-commons/sqlcommons"
+	"github.com/cdleo/go-sqldb/sqlerrors"
+	"github.com/jackc/pgconn"
 	"github.com/lib/pq"
 )
 
@@ -37,26 +39,34 @@ func (s *postgresAdapter) ErrorHandler(err error) error {
 		return nil
 	}
 
+	var code, message string
+	var pgError *pgconn.PgError
 	if pqError, ok := err.(*pq.Error); ok {
-		switch pqError.Code {
-		case "23505":
-			return sqlcommons.UniqueConstraintViolation
-		case "23503":
-			return sqlcommons.IntegrityConstraintViolation
-		case "22001":
-			return sqlcommons.ValueTooLargeForColumn
-		case "22003":
-			return sqlcommons.ValueLargerThanPrecision
-		case "23502":
-			return sqlcommons.CannotSetNullColumn
-		case "22P02":
-			return sqlcommons.InvalidNumericValue
-		case "21000":
-			return sqlcommons.SubqueryReturnsMoreThanOneRow
-		default:
-			return fmt.Errorf("Unhandled PostgreSQL error. Code:[%s] Desc:[%s]", pqError.Code, pqError.Message)
-		}
+		code, message = string(pqError.Code), pqError.Message
+	} else if errors.As(err, &pgError) {
+		code, message = pgError.Code, pgError.Message
 	} else {
 		return err
 	}
+
+	switch code {
+	case "23505":
+		return sqlcommons.UniqueConstraintViolation
+	case "23503":
+		return sqlcommons.IntegrityConstraintViolation
+	case "22001":
+		return sqlcommons.ValueTooLargeForColumn
+	case "22003":
+		return sqlcommons.ValueLargerThanPrecision
+	case "23502":
+		return sqlcommons.CannotSetNullColumn
+	case "22P02":
+		return sqlcommons.InvalidNumericValue
+	case "21000":
+		return sqlcommons.SubqueryReturnsMoreThanOneRow
+	case "57014": //query_canceled, raised by statement_timeout
+		return sqlerrors.QueryTimeout
+	default:
+		return newUnhandledError(err, "Unhandled PostgreSQL error. Code:[%s] Desc:[%s]", code, message)
+	}
 }
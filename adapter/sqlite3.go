@@ -1,10 +1,8 @@
 package adapter
 
 import (
-	"fmt"
-
 	"github.com/cdleo/go-commons/sqlcommons"
-	"github.com/mattn/go-sqlite3"
+	"github.com/cdleo/go-sqldb/sqlerrors"
 )
 
 type sqlite3Adapter struct{}
@@ -17,33 +15,37 @@ func (t *sqlite3Adapter) Translate(query string) string {
 	return query
 }
 
+// ErrorHandler maps the errors of go-sqlite3, or of the pure-Go driver (modernc.org/sqlite) when built without cgo
+// or with the sqlite_purego build tag.
 func (s *sqlite3Adapter) ErrorHandler(err error) error {
 
-	if sqliteError, ok := err.(sqlite3.Error); ok {
+	code, extendedCode, ok := sqliteErrorCodes(err)
+	if !ok {
+		return err
+	}
 
-		if sqliteError.Code == 18 { //SQLITE_TOOBIG
-			return sqlcommons.ValueTooLargeForColumn
+	if code == 18 { //SQLITE_TOOBIG
+		return sqlcommons.ValueTooLargeForColumn
 
-		} else if sqliteError.Code == 19 { //SQLITE_CONSTRAINT
-			if sqliteError.ExtendedCode == 787 || /*SQLITE_CONSTRAINT_FOREIGNKEY*/
-				sqliteError.ExtendedCode == 1555 || /*SQLITE_CONSTRAINT_PRIMARYKEY*/
-				sqliteError.ExtendedCode == 1811 { /*SQLITE_CONSTRAINT_TRIGGER*/
-				return sqlcommons.IntegrityConstraintViolation
+	} else if code == 19 { //SQLITE_CONSTRAINT
+		if extendedCode == 787 || /*SQLITE_CONSTRAINT_FOREIGNKEY*/
+			extendedCode == 1555 || /*SQLITE_CONSTRAINT_PRIMARYKEY*/
+			extendedCode == 1811 { /*SQLITE_CONSTRAINT_TRIGGER*/
+			return sqlcommons.IntegrityConstraintViolation
 
-			} else if sqliteError.ExtendedCode == 1299 { //SQLITE_CONSTRAINT_NOTNULL
-				return sqlcommons.CannotSetNullColumn
+		} else if extendedCode == 1299 { //SQLITE_CONSTRAINT_NOTNULL
+			return sqlcommons.CannotSetNullColumn
 
-			} else if sqliteError.ExtendedCode == 2067 { //SQLITE_CONSTRAINT_UNIQUE
-				return sqlcommons.UniqueConstraintViolation
+		} else if extendedCode == 2067 { //SQLITE_CONSTRAINT_UNIQUE
+			return sqlcommons.UniqueConstraintViolation
 
-			}
-		} else if sqliteError.Code == 25 { //SQLITE_RANGE
-			return sqlcommons.InvalidNumericValue
 		}
+	} else if code == 25 { //SQLITE_RANGE
+		return sqlcommons.InvalidNumericValue
 
-		return fmt.Errorf("Unhandled SQLite3 error. Code:[%s] Extended:[%s] Desc:[%s]", sqliteError.Code, sqliteError.ExtendedCode, sqliteError.Error())
-
-	} else {
-		return err
+	} else if code == 9 { //SQLITE_INTERRUPT
+		return sqlerrors.QueryTimeout
 	}
+
+	return newUnhandledError(err, "Unhandled SQLite3 error. Code:[%d] Extended:[%d] Desc:[%s]", code, extendedCode, err.Error())
 }
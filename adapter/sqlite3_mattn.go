@@ -0,0 +1,14 @@
+//go:build cgo && !sqlite_purego
+
+package adapter
+
+import (
+	"github.com/mattn/go-sqlite3"
+)
+
+func sqliteErrorCodes(err error) (int, int, bool) {
+	if sqliteError, ok := err.(sqlite3.Error); ok {
+		return int(sqliteError.Code), int(sqliteError.ExtendedCode), true
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,15 @@
+//go:build !cgo || sqlite_purego
+
+package adapter
+
+import (
+	"modernc.org/sqlite"
+)
+
+// Without cgo, or with the sqlite_purego build tag, go-sqlite3 is left out and the pure-Go errors are mapped.
+func sqliteErrorCodes(err error) (int, int, bool) {
+	if sqliteError, ok := err.(*sqlite.Error); ok {
+		return sqliteError.Code() & 0xff, sqliteError.Code(), true
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,24 @@
+package adapter
+
+import (
+	"fmt"
+)
+
+// unhandledError reports a native error the adapter doesn't map, keeping it reachable (e.g. by errors.As) for the
+// hooks classifying the errors, as the circuit breaker does.
+type unhandledError struct {
+	message string
+	err     error
+}
+
+func newUnhandledError(err error, format string, args ...interface{}) error {
+	return &unhandledError{fmt.Sprintf(format, args...), err}
+}
+
+func (e *unhandledError) Error() string {
+	return e.message
+}
+
+func (e *unhandledError) Unwrap() error {
+	return e.err
+}
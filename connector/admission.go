@@ -0,0 +1,264 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cdleo/go-sqldb/sqlerrors"
+)
+
+// Priority classes of the statements waiting for admission: the lower, the sooner.
+type Priority int
+
+const (
+	PriorityInteractive Priority = iota
+	PriorityBatch
+)
+
+type priorityCtxKey struct{}
+
+// WithPriority returns a copy of ctx whose statements are admitted with the given priority (interactive by default).
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityCtxKey{}, priority)
+}
+
+func PriorityFromContext(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityCtxKey{}).(Priority); ok {
+		return priority
+	}
+	return PriorityInteractive
+}
+
+type AdmissionStats struct {
+	InFlight int
+	Queued   int
+	Admitted uint64
+	Rejected uint64
+}
+
+type admissionTicket struct {
+	priority    Priority
+	fingerprint string
+	tags        map[string]string
+	ready       chan struct{}
+}
+
+// AdmissionHook limits the number of statements running at once, overall and optionally per fingerprint,
+// per value of a context tag (see WithTag) and per priority class. The statements exceeding a limit wait
+// up to the queue timeout, the higher priority ones being admitted first, and are rejected afterwards with
+// sqlerrors.AdmissionRejected. A query holds its slot until the driver returns, not while its rows are read.
+type AdmissionHook struct {
+	mu               sync.Mutex
+	maxConcurrent    int
+	queueTimeout     time.Duration
+	fingerprintLimit int
+	tagLimits        map[string]int
+	priorityLimits   map[Priority]int
+	inFlight         int
+	byFingerprint    map[string]int
+	byTag            map[string]map[string]int
+	byPriority       map[Priority]int
+	queue            []*admissionTicket
+	admitted         map[*QueryEvent]*admissionTicket
+	stats            AdmissionStats
+}
+
+func NewAdmissionHook(maxConcurrent int, queueTimeout time.Duration) *AdmissionHook {
+	return &AdmissionHook{
+		maxConcurrent:  maxConcurrent,
+		queueTimeout:   queueTimeout,
+		tagLimits:      make(map[string]int),
+		priorityLimits: make(map[Priority]int),
+		byFingerprint:  make(map[string]int),
+		byTag:          make(map[string]map[string]int),
+		byPriority:     make(map[Priority]int),
+		admitted:       make(map[*QueryEvent]*admissionTicket),
+	}
+}
+
+// WithFingerprintLimit limits the statements of the same shape running at once.
+func (h *AdmissionHook) WithFingerprintLimit(limit int) *AdmissionHook {
+	h.fingerprintLimit = limit
+	return h
+}
+
+// WithTagLimit limits the statements running at once with the same value of the tag, e.g. per tenant.
+func (h *AdmissionHook) WithTagLimit(key string, limit int) *AdmissionHook {
+	h.tagLimits[key] = limit
+	h.byTag[key] = make(map[string]int)
+	return h
+}
+
+// WithPriorityLimit limits the statements of a priority class running at once, e.g. to keep room for the interactive ones.
+func (h *AdmissionHook) WithPriorityLimit(priority Priority, limit int) *AdmissionHook {
+	h.priorityLimits[priority] = limit
+	return h
+}
+
+func (h *AdmissionHook) Stats() AdmissionStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	stats := h.stats
+	stats.InFlight = h.inFlight
+	stats.Queued = len(h.queue)
+	return stats
+}
+
+func (h *AdmissionHook) Before(ctx context.Context, event *QueryEvent) error {
+
+	if isExplain(ctx) {
+		return nil
+	}
+
+	ticket := &admissionTicket{
+		priority:    PriorityFromContext(ctx),
+		fingerprint: event.Fingerprint,
+		tags:        TagsFromContext(ctx),
+		ready:       make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	if !h.queuedAhead(ticket) && h.fits(ticket) {
+		h.acquire(event, ticket)
+		h.mu.Unlock()
+		return nil
+	}
+	if h.queueTimeout <= 0 {
+		h.stats.Rejected++
+		h.mu.Unlock()
+		return fmt.Errorf("%w: concurrency limit reached", sqlerrors.AdmissionRejected)
+	}
+	h.enqueue(ticket)
+	h.mu.Unlock()
+
+	timer := time.NewTimer(h.queueTimeout)
+	defer timer.Stop()
+
+	var reason string
+	select {
+	case <-ticket.ready:
+	case <-timer.C:
+		reason = fmt.Sprintf("queued for more than %s", h.queueTimeout)
+	case <-ctx.Done():
+		reason = ctx.Err().Error()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	select {
+	case <-ticket.ready:
+		// Admitted meanwhile
+		h.admitted[event] = ticket
+		return nil
+	default:
+		h.dequeue(ticket)
+		h.admitQueued()
+		h.stats.Rejected++
+		return fmt.Errorf("%w: %s", sqlerrors.AdmissionRejected, reason)
+	}
+}
+
+func (h *AdmissionHook) After(_ context.Context, event *QueryEvent) {
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ticket, ok := h.admitted[event]
+	if !ok {
+		return
+	}
+	delete(h.admitted, event)
+	h.count(ticket, -1)
+	h.admitQueued()
+}
+
+// admitQueued admits, in order, the waiting statements that fit within the limits.
+func (h *AdmissionHook) admitQueued() {
+	for i := 0; i < len(h.queue); {
+		waiting := h.queue[i]
+		if h.fits(waiting) {
+			h.dequeue(waiting)
+			h.count(waiting, 1)
+			close(waiting.ready)
+			continue
+		}
+		i++
+	}
+}
+
+// queuedAhead tells whether a statement of the same or a higher priority is already waiting.
+func (h *AdmissionHook) queuedAhead(ticket *admissionTicket) bool {
+	for _, waiting := range h.queue {
+		if waiting.priority <= ticket.priority {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *AdmissionHook) fits(ticket *admissionTicket) bool {
+
+	if h.maxConcurrent > 0 && h.inFlight >= h.maxConcurrent {
+		return false
+	}
+	if h.fingerprintLimit > 0 && h.byFingerprint[ticket.fingerprint] >= h.fingerprintLimit {
+		return false
+	}
+	if limit, ok := h.priorityLimits[ticket.priority]; ok && h.byPriority[ticket.priority] >= limit {
+		return false
+	}
+	for key, limit := range h.tagLimits {
+		if value := ticket.tags[key]; value != "" && h.byTag[key][value] >= limit {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *AdmissionHook) acquire(event *QueryEvent, ticket *admissionTicket) {
+	h.count(ticket, 1)
+	h.admitted[event] = ticket
+}
+
+func (h *AdmissionHook) count(ticket *admissionTicket, delta int) {
+
+	h.inFlight += delta
+	h.byFingerprint[ticket.fingerprint] += delta
+	if h.byFingerprint[ticket.fingerprint] == 0 {
+		delete(h.byFingerprint, ticket.fingerprint)
+	}
+	h.byPriority[ticket.priority] += delta
+	for key := range h.tagLimits {
+		if value := ticket.tags[key]; value != "" {
+			h.byTag[key][value] += delta
+			if h.byTag[key][value] == 0 {
+				delete(h.byTag[key], value)
+			}
+		}
+	}
+	if delta > 0 {
+		h.stats.Admitted++
+	}
+}
+
+// enqueue keeps the queue sorted by priority, and by arrival within the same priority.
+func (h *AdmissionHook) enqueue(ticket *admissionTicket) {
+	i := len(h.queue)
+	for i > 0 && h.queue[i-1].priority > ticket.priority {
+		i--
+	}
+	h.queue = append(h.queue, nil)
+	copy(h.queue[i+1:], h.queue[i:])
+	h.queue[i] = ticket
+}
+
+func (h *AdmissionHook) dequeue(ticket *admissionTicket) {
+	for i, waiting := range h.queue {
+		if waiting == ticket {
+			h.queue = append(h.queue[:i], h.queue[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package connector
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cdleo/go-commons/logger"
+)
+
+// UserTag is the context tag (see WithTag) the audit hook takes the caller's identity from, by default.
+const UserTag = "user"
+
+const defaultAuditBufferSize = 1024
+
+type auditCtxKey struct{}
+
+type AuditRecord struct {
+	Time         time.Time     `json:"time"`
+	Identity     string        `json:"identity"`
+	Kind         StatementKind `json:"kind"`
+	Command      string        `json:"command"`
+	Statement    string        `json:"statement"`
+	Args         []string      `json:"args"`
+	RowsAffected int64         `json:"rows_affected"`
+	Duration     time.Duration `json:"duration"`
+	Outcome      string        `json:"outcome"`
+	Error        string        `json:"error,omitempty"`
+}
+
+type AuditSink interface {
+	Write(record AuditRecord) error
+}
+
+// AuditHook records every data-modifying (DML) and DDL statement into a sink. Records are handed over to
+// a background writer through a bounded buffer, so a slow sink never blocks the query path: when the
+// buffer is full the record is dropped (and counted).
+type AuditHook struct {
+	sink     AuditSink
+	logger   logger.Logger
+	identity func(ctx context.Context) string
+	records  chan AuditRecord
+	dropped  uint64
+	done     sync.WaitGroup
+	mu       sync.RWMutex
+	closed   bool
+}
+
+func NewAuditHook(sink AuditSink, logger logger.Logger) *AuditHook {
+	return NewAuditHookWithBuffer(sink, logger, defaultAuditBufferSize)
+}
+
+func NewAuditHookWithBuffer(sink AuditSink, logger logger.Logger, bufferSize int) *AuditHook {
+
+	h := &AuditHook{
+		sink:   sink,
+		logger: logger,
+		identity: func(ctx context.Context) string {
+			return TagsFromContext(ctx)[UserTag]
+		},
+		records: make(chan AuditRecord, bufferSize),
+	}
+	h.done.Add(1)
+	go h.run()
+	return h
+}
+
+// WithIdentity sets the function that takes the caller's identity from the statement's context.
+func (h *AuditHook) WithIdentity(identity func(ctx context.Context) string) *AuditHook {
+	h.identity = identity
+	return h
+}
+
+func (h *AuditHook) bind(db *sql.DB, engine string) {
+	if binder, ok := h.sink.(dbBinder); ok {
+		binder.bind(db, engine)
+	}
+}
+
+func (h *AuditHook) Before(_ context.Context, _ *QueryEvent) error {
+	return nil
+}
+
+func (h *AuditHook) After(ctx context.Context, event *QueryEvent) {
+
+	// The audit sink's own writes and the plans of the slow statement log (classified as the statement they
+	// explain) aren't audited
+	if ctx.Value(auditCtxKey{}) != nil || isExplain(ctx) {
+		return
+	}
+	kind, command := ClassifyStatement(event.Query)
+	if kind != StatementDML && kind != StatementDDL {
+		return
+	}
+
+	record := AuditRecord{
+		Time:         event.Start,
+		Identity:     h.identity(ctx),
+		Kind:         kind,
+		Command:      command,
+		Statement:    event.RedactedQuery(),
+		Args:         event.RedactedArgs(),
+		RowsAffected: event.RowsAffected,
+		Duration:     event.Duration,
+		Outcome:      "success",
+	}
+	if event.Err != nil {
+		record.Outcome = "failure"
+		record.Error = event.Err.Error()
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		atomic.AddUint64(&h.dropped, 1)
+		return
+	}
+	select {
+	case h.records <- record:
+	default:
+		atomic.AddUint64(&h.dropped, 1)
+	}
+}
+
+// Dropped returns the number of records discarded because the buffer was full (or the hook closed).
+func (h *AuditHook) Dropped() uint64 {
+	return atomic.LoadUint64(&h.dropped)
+}
+
+// Close flushes the pending records and stops the background writer. It is called by SQLProxy.Close, before the
+// DB is closed.
+func (h *AuditHook) Close() {
+	h.mu.Lock()
+	if !h.closed {
+		h.closed = true
+		close(h.records)
+	}
+	h.mu.Unlock()
+	h.done.Wait()
+}
+
+func (h *AuditHook) run() {
+	defer h.done.Done()
+	for record := range h.records {
+		if err := h.sink.Write(record); err != nil {
+			h.logger.Errorf(err, "Unable to write audit record of %s statement", record.Command)
+		}
+	}
+}
+
+type jsonlAuditSink struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewJSONLAuditSink writes each record as a JSON line, e.g. into an append-only file.
+func NewJSONLAuditSink(w io.Writer) AuditSink {
+	return &jsonlAuditSink{encoder: json.NewEncoder(w)}
+}
+
+func (s *jsonlAuditSink) Write(record AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.encoder.Encode(record)
+}
+
+type channelAuditSink struct {
+	records chan<- AuditRecord
+}
+
+func NewChannelAuditSink(records chan<- AuditRecord) AuditSink {
+	return &channelAuditSink{records}
+}
+
+func (s *channelAuditSink) Write(record AuditRecord) error {
+	s.records <- record
+	return nil
+}
+
+type tableAuditSink struct {
+	table  string
+	db     *sql.DB
+	engine string
+}
+
+// NewTableAuditSink writes the records into a table of the audited DB itself, which must have the columns
+// event_time, identity, kind, command, statement, args, rows_affected, duration_ms, outcome and error.
+func NewTableAuditSink(table string) AuditSink {
+	return &tableAuditSink{table: table}
+}
+
+func (s *tableAuditSink) bind(db *sql.DB, engine string) {
+	s.db = db
+	s.engine = engine
+}
+
+func (s *tableAuditSink) Write(record AuditRecord) error {
+
+	if s.db == nil {
+		return fmt.Errorf("audit table [%s] not bound to a DB", s.table)
+	}
+
+	placeholders := make([]string, 10)
+	for i := range placeholders {
+		placeholders[i] = Placeholder(s.engine, i+1)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (event_time, identity, kind, command, statement, args, rows_affected, duration_ms, outcome, error) VALUES (%s)",
+		s.table, strings.Join(placeholders, ", "))
+
+	ctx := context.WithValue(context.Background(), auditCtxKey{}, true)
+	_, err := s.db.ExecContext(ctx, query, record.Time, record.Identity, string(record.Kind), record.Command, record.Statement,
+		strings.Join(record.Args, ", "), record.RowsAffected, record.Duration.Milliseconds(), record.Outcome, record.Error)
+	return err
+}
@@ -0,0 +1,252 @@
+package connector
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cdleo/go-commons/sqlcommons"
+	"github.com/cdleo/go-sqldb/sqlerrors"
+	"github.com/jackc/pgconn"
+)
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+const defaultProbeTimeout = time.Second
+
+type BreakerStats struct {
+	State    BreakerState
+	Failures int
+	Trips    uint64
+	Probes   uint64
+	Rejected uint64
+}
+
+// CircuitBreaker opens after threshold connection errors (failed connects and pings, broken connections,
+// network errors) within the window, rejecting every statement, ping and connection attempt with
+// sqlerrors.CircuitOpen (which is also a sqlcommons.ConnectionClosed) while open. Once the cooldown elapses
+// it half-opens, letting a single probe through (a ping of its own, or the next attempt): the breaker
+// closes if the probe succeeds, and opens again otherwise.
+type CircuitBreaker struct {
+	mu           sync.Mutex
+	threshold    int
+	window       time.Duration
+	cooldown     time.Duration
+	probeTimeout time.Duration
+	state        BreakerState
+	failures     []time.Time
+	changedAt    time.Time
+	stats        BreakerStats
+	listeners    []func(from BreakerState, to BreakerState)
+	db           *sql.DB
+}
+
+func NewCircuitBreaker(threshold int, window time.Duration, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold:    threshold,
+		window:       window,
+		cooldown:     cooldown,
+		probeTimeout: defaultProbeTimeout,
+		state:        BreakerClosed,
+	}
+}
+
+func (b *CircuitBreaker) WithProbeTimeout(timeout time.Duration) *CircuitBreaker {
+	b.probeTimeout = timeout
+	return b
+}
+
+// OnStateChange registers a callback, called on every transition.
+func (b *CircuitBreaker) OnStateChange(listener func(from BreakerState, to BreakerState)) *CircuitBreaker {
+	b.listeners = append(b.listeners, listener)
+	return b
+}
+
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *CircuitBreaker) Stats() BreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := b.stats
+	stats.State = b.state
+	stats.Failures = len(b.recentFailures(time.Now()))
+	return stats
+}
+
+// Allow returns nil when the DB may be reached. Once the cooldown elapses, the caller is let through as the probe.
+func (b *CircuitBreaker) Allow() error {
+
+	b.mu.Lock()
+	if b.state == BreakerClosed {
+		b.mu.Unlock()
+		return nil
+	}
+	if time.Since(b.changedAt) >= b.cooldown {
+		// Also lets a new probe through if the outcome of the previous one was never reported
+		notify := b.transition(BreakerHalfOpen)
+		b.stats.Probes++
+		b.mu.Unlock()
+		notify()
+		return nil
+	}
+	b.stats.Rejected++
+	b.mu.Unlock()
+	return fmt.Errorf("%w: %w", sqlerrors.CircuitOpen, sqlcommons.ConnectionClosed)
+}
+
+// Report accounts for the outcome of an operation; only connection errors count as failures.
+func (b *CircuitBreaker) Report(err error) {
+	if !errors.Is(err, sqlerrors.CircuitOpen) {
+		b.record(err != nil && isConnectionError(err))
+	}
+}
+
+func (b *CircuitBreaker) bind(db *sql.DB, _ string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.db = db
+}
+
+func (b *CircuitBreaker) Before(ctx context.Context, _ *QueryEvent) error {
+	if isExplain(ctx) {
+		return nil
+	}
+	return b.Allow()
+}
+
+// After reports the outcome of the statements that reached the driver only, so a statement rejected by a hook
+// (e.g. the policy) doesn't close a half-open breaker.
+func (b *CircuitBreaker) After(ctx context.Context, event *QueryEvent) {
+	if !event.Rejected && !isExplain(ctx) {
+		b.Report(event.Err)
+	}
+}
+
+func (b *CircuitBreaker) allowConnect(_ context.Context) error {
+	return b.Allow()
+}
+
+func (b *CircuitBreaker) connected(err error) {
+	if !errors.Is(err, sqlerrors.CircuitOpen) {
+		b.record(err != nil)
+	}
+}
+
+// probe pings the DB once the cooldown elapses, so the breaker doesn't wait for the traffic to half-open.
+func (b *CircuitBreaker) probe() {
+
+	b.mu.Lock()
+	db := b.db
+	b.mu.Unlock()
+
+	if db != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), b.probeTimeout)
+		defer cancel()
+		// The outcome is reported by the interceptor, as for any other ping
+		db.PingContext(ctx)
+	}
+}
+
+func (b *CircuitBreaker) record(failure bool) {
+
+	b.mu.Lock()
+	notify := func() {}
+	now := time.Now()
+	switch {
+	case b.state == BreakerHalfOpen && failure:
+		notify = b.transition(BreakerOpen)
+	case b.state == BreakerHalfOpen:
+		notify = b.transition(BreakerClosed)
+	case b.state == BreakerClosed && failure:
+		b.failures = append(b.recentFailures(now), now)
+		if len(b.failures) >= b.threshold {
+			notify = b.transition(BreakerOpen)
+		}
+	}
+	b.mu.Unlock()
+	notify()
+}
+
+// transition changes the state (the lock being held) and returns the notification of the listeners.
+func (b *CircuitBreaker) transition(to BreakerState) func() {
+
+	from := b.state
+	b.state = to
+	b.changedAt = time.Now()
+	switch to {
+	case BreakerOpen:
+		b.stats.Trips++
+		time.AfterFunc(b.cooldown, b.probe)
+	case BreakerClosed:
+		b.failures = nil
+	}
+
+	listeners := b.listeners
+	return func() {
+		for _, listener := range listeners {
+			listener(from, to)
+		}
+	}
+}
+
+func (b *CircuitBreaker) recentFailures(now time.Time) []time.Time {
+	for i, failure := range b.failures {
+		if now.Sub(failure) < b.window {
+			return b.failures[i:]
+		}
+	}
+	return nil
+}
+
+// isConnectionError tells whether the error means the DB can't be reached, as opposed to a failed statement.
+// The hooks get the errors mapped by the adapter, which keeps the native errors it doesn't map reachable.
+func isConnectionError(err error) bool {
+
+	if errors.Is(err, sqlerrors.CircuitOpen) || errors.Is(err, sqlerrors.QueryTimeout) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sqlcommons.ConnectionClosed) || errors.Is(err, sqlcommons.ConnectionFailed) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var netError net.Error
+	if errors.As(err, &netError) {
+		return true
+	}
+
+	var pgError *pgconn.PgError
+	if errors.As(err, &pgError) {
+		return pgError.Code[:2] == "08" || pgError.Code == "57P01" || pgError.Code == "57P02" || pgError.Code == "57P03"
+	}
+
+	if code, ok := oraErrorCode(err); ok {
+		switch code {
+		case 1033, 1034, 1089, 3113, 3114, 3135, 12170, 12514, 12528, 12537, 12541, 12543, 12547:
+			return true
+		}
+		return false
+	}
+
+	if code, ok := sqliteErrorCode(err); ok {
+		return code == 14 /*SQLITE_CANTOPEN*/ || code == 10 /*SQLITE_IOERR*/
+	}
+	return false
+}
@@ -0,0 +1,287 @@
+package connector
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cdleo/go-commons/sqlcommons"
+	proxy "github.com/cdleo/go-sql-proxy"
+	"github.com/cdleo/go-sqldb/sqlerrors"
+	"github.com/jackc/pgconn"
+	pgx "github.com/jackc/pgx/v4"
+	stdlib "github.com/jackc/pgx/v4/stdlib"
+)
+
+const bulkChunkRows = 1000
+
+// sqlite3MaxVariables is the default SQLITE_MAX_VARIABLE_NUMBER since SQLite 3.32.
+const sqlite3MaxVariables = 32766
+
+// BulkInserter is implemented by the connectors with a fast path to insert many rows at once.
+// The rows are inserted all or none, and a failure caused by a given row is reported as a
+// *sqlerrors.BulkInsertError.
+type BulkInserter interface {
+	BulkInsert(ctx context.Context, db *sql.DB, translator sqlcommons.SQLAdapter, table string, columns []string, rows [][]interface{}) (int64, error)
+}
+
+// bulkStatement inserts the rows chunk by chunk within a transaction. When a chunk fails, its rows are
+// inserted one by one to tell the failing one.
+type bulkStatement struct {
+	chunkRows int
+	query     func(rows int) string
+	args      func(chunk [][]interface{}) ([]interface{}, error)
+}
+
+func (b bulkStatement) insert(ctx context.Context, db *sql.DB, rows [][]interface{}) (int64, error) {
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var inserted int64
+	for start := 0; start < len(rows); start += b.chunkRows {
+		end := start + b.chunkRows
+		if end > len(rows) {
+			end = len(rows)
+		}
+		if err := b.insertChunk(ctx, tx, rows[start:end], start); err != nil {
+			return 0, err
+		}
+		inserted += int64(end - start)
+	}
+	return inserted, tx.Commit()
+}
+
+func (b bulkStatement) insertChunk(ctx context.Context, tx *sql.Tx, chunk [][]interface{}, offset int) error {
+
+	args, err := b.args(chunk)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT bulk_chunk"); err != nil {
+		return err
+	}
+	if _, chunkErr := tx.ExecContext(ctx, b.query(len(chunk)), args...); chunkErr != nil {
+		if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT bulk_chunk"); err != nil {
+			return chunkErr
+		}
+		for i := range chunk {
+			args, err := b.args(chunk[i : i+1])
+			if err != nil {
+				return &sqlerrors.BulkInsertError{Row: offset + i, Err: err}
+			}
+			if _, err := tx.ExecContext(ctx, b.query(1), args...); err != nil {
+				return &sqlerrors.BulkInsertError{Row: offset + i, Err: err}
+			}
+		}
+		return chunkErr
+	}
+	return nil
+}
+
+// multiRowInsert builds an "INSERT ... VALUES (...), (...)" statement with as many rows as the variables limit allows.
+func multiRowInsert(engine string, table string, columns []string, maxVariables int) bulkStatement {
+
+	chunkRows := bulkChunkRows
+	if maxVariables/len(columns) < chunkRows {
+		chunkRows = maxVariables / len(columns)
+	}
+
+	return bulkStatement{
+		chunkRows: chunkRows,
+		query: func(rows int) string {
+			values := make([]string, rows)
+			n := 0
+			for i := range values {
+				placeholders := make([]string, len(columns))
+				for j := range placeholders {
+					n++
+					placeholders[j] = Placeholder(engine, n)
+				}
+				values[i] = "(" + strings.Join(placeholders, ", ") + ")"
+			}
+			return fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", table, strings.Join(columns, ", "), strings.Join(values, ", "))
+		},
+		args: func(chunk [][]interface{}) ([]interface{}, error) {
+			args := make([]interface{}, 0, len(chunk)*len(columns))
+			for _, row := range chunk {
+				if len(row) != len(columns) {
+					return nil, fmt.Errorf("%d values for %d columns", len(row), len(columns))
+				}
+				args = append(args, row...)
+			}
+			return args, nil
+		},
+	}
+}
+
+// arrayInsert builds an "INSERT ... VALUES (:1, ...)" statement bound to one array per column, which godror
+// executes at once (array DML).
+func arrayInsert(table string, columns []string) bulkStatement {
+
+	placeholders := make([]string, len(columns))
+	for i := range placeholders {
+		placeholders[i] = Placeholder(oracleEngine, i+1)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+
+	return bulkStatement{
+		chunkRows: bulkChunkRows,
+		query: func(_ int) string {
+			return query
+		},
+		args: func(chunk [][]interface{}) ([]interface{}, error) {
+			args := make([]interface{}, len(columns))
+			for i := range columns {
+				values := make([]interface{}, len(chunk))
+				for j, row := range chunk {
+					if len(row) != len(columns) {
+						return nil, fmt.Errorf("%d values for %d columns", len(row), len(columns))
+					}
+					values[j] = row[i]
+				}
+				column, err := arrayOf(values)
+				if err != nil {
+					return nil, fmt.Errorf("column [%s]: %w", columns[i], err)
+				}
+				args[i] = column
+			}
+			return args, nil
+		},
+	}
+}
+
+// arrayOf returns the values as a typed slice. Oracle stores the empty strings and byte slices as NULLs, as
+// godror does with the zero times, so the nil values are bound as such, or as sql.Null* numbers.
+func arrayOf(values []interface{}) (interface{}, error) {
+
+	var elemType reflect.Type
+	hasNil := false
+	for i, value := range values {
+		if value == nil {
+			hasNil = true
+			continue
+		}
+		converted, err := driver.DefaultParameterConverter.ConvertValue(value)
+		if err != nil {
+			return nil, err
+		}
+		if elemType == nil {
+			elemType = reflect.TypeOf(converted)
+		} else if reflect.TypeOf(converted) != elemType {
+			return nil, fmt.Errorf("mixed types %s and %T", elemType, converted)
+		}
+		values[i] = converted
+	}
+	if elemType == nil {
+		elemType = reflect.TypeOf("")
+	}
+
+	switch {
+	case hasNil && elemType.Kind() == reflect.Int64:
+		array := make([]sql.NullInt64, len(values))
+		for i, value := range values {
+			if value != nil {
+				array[i] = sql.NullInt64{Int64: value.(int64), Valid: true}
+			}
+		}
+		return array, nil
+	case hasNil && elemType.Kind() == reflect.Float64:
+		array := make([]sql.NullFloat64, len(values))
+		for i, value := range values {
+			if value != nil {
+				array[i] = sql.NullFloat64{Float64: value.(float64), Valid: true}
+			}
+		}
+		return array, nil
+	default:
+		array := reflect.MakeSlice(reflect.SliceOf(elemType), len(values), len(values))
+		for i, value := range values {
+			if value != nil {
+				array.Index(i).Set(reflect.ValueOf(value))
+			}
+		}
+		return array.Interface(), nil
+	}
+}
+
+var copyLineRegExp = regexp.MustCompile(`COPY .*, line (\d+)`)
+
+// copyFrom streams the rows with the COPY protocol of PostgreSQL, through the pgx connection underneath the proxy.
+func copyFrom(ctx context.Context, db *sql.DB, translator sqlcommons.SQLAdapter, table string, columns []string, rows [][]interface{}) (int64, error) {
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	identifiers := make([]string, len(columns))
+	for i, column := range columns {
+		identifiers[i] = strings.ToLower(column)
+	}
+
+	var inserted int64
+	err = conn.Raw(func(driverConn interface{}) error {
+
+		pgxConn, ok := unwrapConn(driverConn).(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection %T", unwrapConn(driverConn))
+		}
+
+		tx, err := pgxConn.Conn().Begin(ctx)
+		if err != nil {
+			return translator.ErrorHandler(err)
+		}
+		defer tx.Rollback(ctx)
+
+		for start := 0; start < len(rows); start += bulkChunkRows {
+			end := start + bulkChunkRows
+			if end > len(rows) {
+				end = len(rows)
+			}
+			n, err := tx.CopyFrom(ctx, pgx.Identifier(strings.Split(strings.ToLower(table), ".")), identifiers, pgx.CopyFromRows(rows[start:end]))
+			if err != nil {
+				return copyError(translator, err, start)
+			}
+			inserted += n
+		}
+		return translator.ErrorHandler(tx.Commit(ctx))
+	})
+	if err != nil {
+		return 0, err
+	}
+	return inserted, nil
+}
+
+// copyError tells the failing row from the context of the error ("COPY table, line n").
+func copyError(translator sqlcommons.SQLAdapter, err error, offset int) error {
+
+	if pgError, ok := err.(*pgconn.PgError); ok {
+		if m := copyLineRegExp.FindStringSubmatch(pgError.Where); m != nil {
+			if line, convErr := strconv.Atoi(m[1]); convErr == nil {
+				return &sqlerrors.BulkInsertError{Row: offset + line - 1, Err: translator.ErrorHandler(err)}
+			}
+		}
+	}
+	return translator.ErrorHandler(err)
+}
+
+// unwrapConn returns the driver's own connection underneath the proxy and the wrapper.
+func unwrapConn(driverConn interface{}) interface{} {
+	if proxyConn, ok := driverConn.(*proxy.Conn); ok {
+		driverConn = proxyConn.Conn
+	}
+	if wrapped, ok := driverConn.(*wrappedConn); ok {
+		driverConn = wrapped.Unwrap()
+	}
+	return driverConn
+}
@@ -0,0 +1,121 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// CommenterHook appends a sqlcommenter comment (e.g. /*app='x',route='y',traceparent='...'*/) to each statement,
+// so the DB activity views can attribute it. It runs after the translation, appends the comment at the end
+// of the statement (keeping any leading Oracle hint intact) and leaves the PL/SQL blocks, the DDL and the
+// statements already ending with a comment untouched. Prepared statements keep the text they were prepared with.
+type CommenterHook struct {
+	static      map[string]string
+	contextKeys map[string]interface{}
+	tags        []string
+}
+
+func NewCommenterHook() *CommenterHook {
+	return &CommenterHook{
+		static:      make(map[string]string),
+		contextKeys: make(map[string]interface{}),
+	}
+}
+
+// WithStaticTag adds a tag with a fixed value, such as the application name.
+func (h *CommenterHook) WithStaticTag(key string, value string) *CommenterHook {
+	h.static[key] = value
+	return h
+}
+
+// WithContextValue adds a tag whose value is taken from ctx.Value(ctxKey), e.g. a trace set by a middleware.
+func (h *CommenterHook) WithContextValue(key string, ctxKey interface{}) *CommenterHook {
+	h.contextKeys[key] = ctxKey
+	return h
+}
+
+// WithTags restricts the tags taken from the context (see WithTag) to the given keys. All of them are included by default.
+func (h *CommenterHook) WithTags(keys ...string) *CommenterHook {
+	h.tags = append(h.tags, keys...)
+	return h
+}
+
+func (h *CommenterHook) Before(ctx context.Context, event *QueryEvent) error {
+	if comment := h.comment(ctx); comment != "" {
+		event.Query = appendComment(event.Query, comment)
+	}
+	return nil
+}
+
+func (h *CommenterHook) After(_ context.Context, _ *QueryEvent) {}
+
+func (h *CommenterHook) comment(ctx context.Context) string {
+
+	values := make(map[string]string)
+	for key, value := range h.static {
+		values[key] = value
+	}
+	for key, ctxKey := range h.contextKeys {
+		if value := ctx.Value(ctxKey); value != nil {
+			values[key] = fmt.Sprintf("%v", value)
+		}
+	}
+	for key, value := range TagsFromContext(ctx) {
+		if len(h.tags) == 0 || contains(h.tags, key) {
+			values[key] = value
+		}
+	}
+	if len(values) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(values))
+	for key, value := range values {
+		pairs = append(pairs, fmt.Sprintf("%s='%s'", escapeCommentValue(key), escapeCommentValue(value)))
+	}
+	sort.Strings(pairs)
+	return "/*" + strings.Join(pairs, ",") + "*/"
+}
+
+func appendComment(query string, comment string) string {
+
+	trimmed := strings.TrimRight(query, " \t\r\n")
+	if strings.HasSuffix(trimmed, "*/") || isBlockOrDDL(trimmed) {
+		return query
+	}
+	if strings.HasSuffix(trimmed, ";") {
+		return strings.TrimRight(strings.TrimSuffix(trimmed, ";"), " \t\r\n") + " " + comment + ";"
+	}
+	return trimmed + " " + comment
+}
+
+func isBlockOrDDL(query string) bool {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "BEGIN", "DECLARE", "CREATE", "ALTER", "DROP":
+		return true
+	default:
+		return false
+	}
+}
+
+// escapeCommentValue url-encodes the value as the sqlcommenter spec requires, which also encodes the quotes and
+// the asterisks, so it can't close the quote nor the comment.
+func escapeCommentValue(value string) string {
+	return url.PathEscape(value)
+}
+
+func contains(items []string, item string) bool {
+	for _, candidate := range items {
+		if candidate == item {
+			return true
+		}
+	}
+	return false
+}
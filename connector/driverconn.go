@@ -0,0 +1,295 @@
+package connector
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+)
+
+// wrappedConnector wraps the driver's connections underneath the interceptor, so the SecretArg
+// bind arguments survive the database/sql conversion (allowing the hooks to redact them) and are
+// unwrapped right before reaching the driver (unless the driver keeps them, as the fixture connectors do, to leave
+// them out of the fixtures). It also applies the default timeouts and fills the gaps
+// of the drivers lacking support for the read-only transactions.
+type wrappedConnector struct {
+	driver.Connector
+	engine      string
+	timeouts    Timeouts
+	keepSecrets bool
+}
+
+func (c *wrappedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedConn{Conn: conn, engine: c.engine, timeouts: c.timeouts, keepSecrets: c.keepSecrets}, nil
+}
+
+// Close closes the driver's connector, if it holds resources of its own (called by the DB's Close).
+func (c *wrappedConnector) Close() error {
+	if closer, ok := c.Connector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+type wrappedConn struct {
+	driver.Conn
+	engine      string
+	timeouts    Timeouts
+	keepSecrets bool
+	pending     chan error
+}
+
+func (c *wrappedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if prepCtx, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = prepCtx.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &wrappedStmt{stmt, c}, nil
+}
+
+func (c *wrappedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+
+	ctx, cancel := c.timeouts.withTimeout(ctx, OpBegin)
+	defer cancel()
+
+	tx, err := c.begin(ctx, opts)
+	if err != nil {
+		return nil, timeoutError(ctx, err)
+	}
+	if timeout := c.timeouts.For(OpCommit); timeout > 0 {
+		return &timeoutTx{tx, c, timeout}, nil
+	}
+	return tx, nil
+}
+
+func (c *wrappedConn) begin(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if opts.ReadOnly && c.engine == sqlite3Engine {
+		return c.beginQueryOnly(ctx, opts)
+	}
+	if beginCtx, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginCtx.BeginTx(ctx, opts)
+	}
+	if opts.ReadOnly || opts.Isolation != driver.IsolationLevel(0) {
+		return nil, errors.New("driver does not support non-default transaction options")
+	}
+	return c.Conn.Begin() //nolint:staticcheck
+}
+
+// beginQueryOnly emulates a read-only transaction on SQLite3, which ignores the option, by switching
+// the connection to query_only until the transaction ends.
+func (c *wrappedConn) beginQueryOnly(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+
+	if _, err := c.ExecContext(ctx, "PRAGMA query_only = ON", nil); err != nil {
+		return nil, err
+	}
+	opts.ReadOnly = false
+	tx, err := c.begin(ctx, opts)
+	if err != nil {
+		c.ExecContext(context.Background(), "PRAGMA query_only = OFF", nil)
+		return nil, err
+	}
+	return &queryOnlyTx{tx, c}, nil
+}
+
+func (c *wrappedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execCtx, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	ctx, cancel := c.timeouts.withTimeout(ctx, OpExec)
+	defer cancel()
+
+	result, err := execCtx.ExecContext(ctx, query, c.driverArgs(args))
+	return result, timeoutError(ctx, err)
+}
+
+func (c *wrappedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryCtx, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	return c.timeouts.query(ctx, func(ctx context.Context) (driver.Rows, error) {
+		return queryCtx.QueryContext(ctx, query, c.driverArgs(args))
+	})
+}
+
+func (c *wrappedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *wrappedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+// Close defers the closing of a connection still running a timed out commit until the driver returns.
+func (c *wrappedConn) Close() error {
+	if c.pending != nil {
+		go func() {
+			<-c.pending
+			c.Conn.Close()
+		}()
+		return nil
+	}
+	return c.Conn.Close()
+}
+
+func (c *wrappedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *wrappedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	return checkNamedValue(nv, c.Conn)
+}
+
+// Unwrap returns the driver's own connection, e.g. to reach engine specific APIs.
+func (c *wrappedConn) Unwrap() driver.Conn {
+	return c.Conn
+}
+
+type queryOnlyTx struct {
+	driver.Tx
+	conn *wrappedConn
+}
+
+func (t *queryOnlyTx) Commit() error {
+	err := t.Tx.Commit()
+	_, resetErr := t.conn.ExecContext(context.Background(), "PRAGMA query_only = OFF", nil)
+	if err != nil {
+		return err
+	}
+	return resetErr
+}
+
+func (t *queryOnlyTx) Rollback() error {
+	err := t.Tx.Rollback()
+	_, resetErr := t.conn.ExecContext(context.Background(), "PRAGMA query_only = OFF", nil)
+	if err != nil {
+		return err
+	}
+	return resetErr
+}
+
+type wrappedStmt struct {
+	driver.Stmt
+	conn *wrappedConn
+}
+
+func (s *wrappedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if execCtx, ok := s.Stmt.(driver.StmtExecContext); ok {
+		ctx, cancel := s.conn.timeouts.withTimeout(ctx, OpExec)
+		defer cancel()
+
+		result, err := execCtx.ExecContext(ctx, s.conn.driverArgs(args))
+		return result, timeoutError(ctx, err)
+	}
+	values, err := namedValuesToValues(unwrapSecrets(args))
+	if err != nil {
+		return nil, err
+	}
+	return s.Stmt.Exec(values) //nolint:staticcheck
+}
+
+func (s *wrappedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if queryCtx, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		return s.conn.timeouts.query(ctx, func(ctx context.Context) (driver.Rows, error) {
+			return queryCtx.QueryContext(ctx, s.conn.driverArgs(args))
+		})
+	}
+	values, err := namedValuesToValues(unwrapSecrets(args))
+	if err != nil {
+		return nil, err
+	}
+	return s.Stmt.Query(values) //nolint:staticcheck
+}
+
+func (s *wrappedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if _, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checkNamedValue(nv, s.Stmt)
+	}
+	return checkNamedValue(nv, s.conn.Conn)
+}
+
+// checkNamedValue delegates the check to the driver (falling back to the default conversion),
+// keeping the SecretArg marker around the converted value.
+func checkNamedValue(nv *driver.NamedValue, checker interface{}) error {
+
+	secret, isSecret := nv.Value.(SecretArg)
+	if !isSecret {
+		if nvc, ok := checker.(driver.NamedValueChecker); ok {
+			return nvc.CheckNamedValue(nv)
+		}
+		return driver.ErrSkip
+	}
+
+	inner := driver.NamedValue{Name: nv.Name, Ordinal: nv.Ordinal, Value: secret.value}
+	err := driver.ErrSkip
+	if nvc, ok := checker.(driver.NamedValueChecker); ok {
+		err = nvc.CheckNamedValue(&inner)
+	}
+	if err == driver.ErrSkip {
+		inner.Value, err = driver.DefaultParameterConverter.ConvertValue(inner.Value)
+	}
+	if err != nil {
+		return err
+	}
+	nv.Value = SecretArg{inner.Value}
+	return nil
+}
+
+func (c *wrappedConn) driverArgs(args []driver.NamedValue) []driver.NamedValue {
+	if c.keepSecrets {
+		return args
+	}
+	return unwrapSecrets(args)
+}
+
+func unwrapSecrets(args []driver.NamedValue) []driver.NamedValue {
+
+	var unwrapped []driver.NamedValue
+	for i, arg := range args {
+		if secret, ok := arg.Value.(SecretArg); ok {
+			if unwrapped == nil {
+				unwrapped = make([]driver.NamedValue, len(args))
+				copy(unwrapped, args)
+			}
+			unwrapped[i].Value = secret.value
+		}
+	}
+	if unwrapped == nil {
+		return args
+	}
+	return unwrapped
+}
+
+func namedValuesToValues(args []driver.NamedValue) ([]driver.Value, error) {
+
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("driver does not support the use of Named Parameters")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
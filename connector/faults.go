@@ -0,0 +1,426 @@
+package connector
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cdleo/go-commons/logger"
+	"github.com/cdleo/go-commons/sqlcommons"
+	"github.com/cdleo/go-sqldb/sqlerrors"
+	"github.com/jackc/pgconn"
+)
+
+// OpPing identifies the pings of the pool, which only the faults tell apart from the other operations.
+const OpPing Operation = "Ping"
+
+type faultKind int
+
+const (
+	faultLatency faultKind = iota
+	faultError
+	faultDeadlock
+	faultUniqueViolation
+	faultDrop
+)
+
+var (
+	errConnectionDropped = fmt.Errorf("%w: connection dropped (%w)", sqlerrors.InjectedFault, io.ErrUnexpectedEOF)
+	errCommitFailed      = fmt.Errorf("%w: commit failed", sqlerrors.InjectedFault)
+)
+
+// Fault is a misbehaviour of the DB, injected by the FaultInjector on every call of its operations unless
+// narrowed down to the statements matching a pattern, to the n-th call or to a probability.
+type Fault struct {
+	kind        faultKind
+	ops         []Operation
+	delay       time.Duration
+	err         error
+	pattern     *regexp.Regexp
+	nth         int
+	probability float64
+	calls       int
+	injected    atomic.Int64
+}
+
+func newFault(kind faultKind, ops ...Operation) *Fault {
+	return &Fault{kind: kind, ops: ops, probability: 1}
+}
+
+// Latency delays the statements (until their context is done, at most).
+func Latency(delay time.Duration) *Fault {
+	fault := newFault(faultLatency, OpExec, OpQuery)
+	fault.delay = delay
+	return fault
+}
+
+// Fail fails the statements with err, as if the driver returned it.
+func Fail(err error) *Fault {
+	fault := newFault(faultError, OpExec, OpQuery)
+	fault.err = err
+	return fault
+}
+
+// Deadlock fails the statements with the deadlock error of the engine (SQLITE_BUSY on SQLite3). The Oracle and
+// pure-Go SQLite3 errors can't be built outside of their drivers, so a plain error stands in for them, left unmapped
+// by the adapter.
+func Deadlock() *Fault {
+	return newFault(faultDeadlock, OpExec, OpQuery)
+}
+
+// UniqueViolation fails the statements with the unique constraint violation of the engine. On Oracle and on the
+// pure-Go SQLite3 driver, sqlcommons.UniqueConstraintViolation itself stands in for it, bypassing the code mapping
+// of the adapter.
+func UniqueViolation() *Fault {
+	return newFault(faultUniqueViolation, OpExec, OpQuery)
+}
+
+// DropConnection breaks the connection once the statement reached the DB, so the statement fails (with an
+// io.ErrUnexpectedEOF) without telling whether it was applied. The pool then discards the connection.
+func DropConnection() *Fault {
+	return newFault(faultDrop, OpExec, OpQuery)
+}
+
+// FailCommit rolls the transactions back instead of committing them.
+func FailCommit() *Fault {
+	fault := newFault(faultError, OpCommit)
+	fault.err = errCommitFailed
+	return fault
+}
+
+// FailPing fails the pings, breaking the connection.
+func FailPing() *Fault {
+	return newFault(faultDrop, OpPing)
+}
+
+// On sets the operations the fault is injected into.
+func (f *Fault) On(ops ...Operation) *Fault {
+	f.ops = ops
+	return f
+}
+
+// OnQuery restricts the fault to the (translated) statements matching the expression.
+func (f *Fault) OnQuery(expr string) *Fault {
+	f.pattern = regexp.MustCompile(expr)
+	return f
+}
+
+// OnCall restricts the fault to the n-th matching call (starting at 1).
+func (f *Fault) OnCall(n int) *Fault {
+	f.nth = n
+	return f
+}
+
+// WithProbability injects the fault into the given share of the matching calls.
+func (f *Fault) WithProbability(probability float64) *Fault {
+	f.probability = probability
+	return f
+}
+
+// Injected returns how many times the fault has been injected.
+func (f *Fault) Injected() int {
+	return int(f.injected.Load())
+}
+
+func (f *Fault) matches(op Operation, query string) bool {
+	for _, faultOp := range f.ops {
+		if faultOp == op {
+			return f.pattern == nil || (query != "" && f.pattern.MatchString(query))
+		}
+	}
+	return false
+}
+
+func (f *Fault) trigger(random *rand.Rand) bool {
+	f.calls++
+	if f.nth > 0 && f.calls != f.nth {
+		return false
+	}
+	if f.probability < 1 && random.Float64() >= f.probability {
+		return false
+	}
+	f.injected.Add(1)
+	return true
+}
+
+// error returns the error of the fault in the native form of the engine, for the adapter to map it. The Oracle
+// (and pure-Go SQLite3) errors can't be built outside of their drivers, so the portable ones stand in for them.
+func (f *Fault) error(engine string) error {
+	switch f.kind {
+	case faultDeadlock:
+		switch engine {
+		case sqlite3Engine:
+			return sqliteError(5 /*SQLITE_BUSY*/, 5, errors.New("database is locked"))
+		case postgresEngine:
+			return &pgconn.PgError{Severity: "ERROR", Code: "40P01", Message: "deadlock detected"}
+		default:
+			return errors.New("ORA-00060: deadlock detected while waiting for resource")
+		}
+	case faultUniqueViolation:
+		switch engine {
+		case sqlite3Engine:
+			return sqliteError(19 /*SQLITE_CONSTRAINT*/, 2067 /*SQLITE_CONSTRAINT_UNIQUE*/, sqlcommons.UniqueConstraintViolation)
+		case postgresEngine:
+			return &pgconn.PgError{Severity: "ERROR", Code: "23505", Message: "duplicate key value violates unique constraint"}
+		default:
+			return sqlcommons.UniqueConstraintViolation
+		}
+	default:
+		return f.err
+	}
+}
+
+// FaultInjector wraps a connector, injecting faults into its statements, transactions and pings, e.g. to test
+// the reconnection (see SQLProxy.IsOpen), the retries or the error mapping. A call may trigger several faults,
+// injected in order (e.g. a latency, then an error).
+type FaultInjector struct {
+	interception
+	inner  sqlcommons.SQLConnector
+	mu     sync.Mutex
+	faults []*Fault
+	random *rand.Rand
+}
+
+func NewFaultInjector(inner sqlcommons.SQLConnector, faults ...*Fault) *FaultInjector {
+	return &FaultInjector{
+		inner:  inner,
+		faults: faults,
+		random: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// WithSeed makes the faults injected by probability reproducible.
+func (i *FaultInjector) WithSeed(seed int64) *FaultInjector {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.random = rand.New(rand.NewSource(seed))
+	return i
+}
+
+// Inject adds faults, even once the DB is opened.
+func (i *FaultInjector) Inject(faults ...*Fault) *FaultInjector {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.faults = append(i.faults, faults...)
+	return i
+}
+
+// Clear removes every fault.
+func (i *FaultInjector) Clear() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.faults = nil
+}
+
+func (i *FaultInjector) Open(logger logger.Logger, translator sqlcommons.SQLAdapter) (*sql.DB, error) {
+
+	// The inner DB maps the native errors, but leaves the logging to the outer one
+	innerDB, err := i.inner.Open(noLogger, translator)
+	if err != nil {
+		return nil, err
+	}
+
+	engine := i.Engine()
+	return openProxy(engine, logger, translator, &innerConnector{
+		connect: func(ctx context.Context) (driver.Conn, error) {
+			conn, err := innerDB.Conn(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return &faultyDriverConn{conn: conn, engine: engine, injector: i}, nil
+		},
+		close: innerDB.Close,
+	}, i.interception), nil
+}
+
+func (i *FaultInjector) GetNextSequenceQuery(sequenceName string) string {
+	return i.inner.GetNextSequenceQuery(sequenceName)
+}
+
+func (i *FaultInjector) Engine() string {
+	return engineOf(i.inner)
+}
+
+func (i *FaultInjector) triggered(op Operation, query string) []*Fault {
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var triggered []*Fault
+	for _, fault := range i.faults {
+		if fault.matches(op, query) && fault.trigger(i.random) {
+			triggered = append(triggered, fault)
+		}
+	}
+	return triggered
+}
+
+// faultyDriverConn runs the (already translated) statements on a connection of the inner DB, unless a fault
+// says otherwise.
+type faultyDriverConn struct {
+	conn     *sql.Conn
+	tx       *sql.Tx
+	engine   string
+	injector *FaultInjector
+	broken   bool
+}
+
+func (c *faultyDriverConn) target() sqlQuerier {
+	if c.tx != nil {
+		return c.tx
+	}
+	return c.conn
+}
+
+// run injects the faults triggered by the call around it.
+func (c *faultyDriverConn) run(ctx context.Context, op Operation, query string, call func() error) error {
+
+	if c.broken {
+		return driver.ErrBadConn
+	}
+
+	dropped := false
+	for _, fault := range c.injector.triggered(op, query) {
+		switch fault.kind {
+		case faultLatency:
+			select {
+			case <-time.After(fault.delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		case faultDrop:
+			dropped = true
+		default:
+			return fault.error(c.engine)
+		}
+	}
+
+	err := call()
+	if dropped {
+		c.drop()
+		return errConnectionDropped
+	}
+	return err
+}
+
+// drop breaks the connection, rolling back its transaction as the DB would.
+func (c *faultyDriverConn) drop() {
+	if c.tx != nil {
+		c.tx.Rollback()
+		c.tx = nil
+	}
+	c.conn.Close()
+	c.broken = true
+}
+
+func (c *faultyDriverConn) Prepare(query string) (driver.Stmt, error) {
+	return &connStmt{c, query}, nil
+}
+
+func (c *faultyDriverConn) Close() error {
+	if c.broken {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+func (c *faultyDriverConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *faultyDriverConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	err := c.run(ctx, OpBegin, "", func() error {
+		tx, err := c.conn.BeginTx(ctx, &sql.TxOptions{Isolation: sql.IsolationLevel(opts.Isolation), ReadOnly: opts.ReadOnly})
+		c.tx = tx
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &faultyTx{c}, nil
+}
+
+func (c *faultyDriverConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	var result sql.Result
+	err := c.run(ctx, OpExec, query, func() (err error) {
+		result, err = c.target().ExecContext(WithNativeSQL(ctx), query, sqlArgs(args)...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *faultyDriverConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	var read *fixtureRows
+	err := c.run(ctx, OpQuery, query, func() error {
+		rows, err := c.target().QueryContext(WithNativeSQL(ctx), query, sqlArgs(args)...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		read, err = readRows(rows)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return read, nil
+}
+
+func (c *faultyDriverConn) Ping(ctx context.Context) error {
+	return c.run(ctx, OpPing, "", func() error {
+		return c.conn.PingContext(ctx)
+	})
+}
+
+func (c *faultyDriverConn) ResetSession(_ context.Context) error {
+	if c.broken {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func (c *faultyDriverConn) IsValid() bool {
+	return !c.broken
+}
+
+type faultyTx struct {
+	conn *faultyDriverConn
+}
+
+func (t *faultyTx) Commit() error {
+	return t.end(OpCommit, (*sql.Tx).Commit)
+}
+
+func (t *faultyTx) Rollback() error {
+	return t.end(OpRollback, (*sql.Tx).Rollback)
+}
+
+// end rolls the transaction back if a fault keeps it from ending as asked.
+func (t *faultyTx) end(op Operation, end func(*sql.Tx) error) error {
+
+	tx := t.conn.tx
+	if tx == nil {
+		return driver.ErrBadConn
+	}
+	ended := false
+	err := t.conn.run(context.Background(), op, "", func() error {
+		ended = true
+		return end(tx)
+	})
+	if !ended {
+		tx.Rollback()
+	}
+	t.conn.tx = nil
+	return err
+}
@@ -0,0 +1,274 @@
+package connector
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cdleo/go-commons/sqlcommons"
+	"github.com/cdleo/go-sqldb/sqlerrors"
+)
+
+// fixture is the file written by the recording connector and served by the replay one.
+type fixture struct {
+	Engine       string        `json:"engine"`
+	Interactions []interaction `json:"interactions"`
+}
+
+type interaction struct {
+	Operation    Operation        `json:"op"`
+	Query        string           `json:"query,omitempty"`
+	Args         []fixtureValue   `json:"args,omitempty"`
+	Columns      []string         `json:"columns,omitempty"`
+	Rows         [][]fixtureValue `json:"rows,omitempty"`
+	LastInsertID *int64           `json:"lastInsertId,omitempty"`
+	RowsAffected *int64           `json:"rowsAffected,omitempty"`
+	Error        string           `json:"error,omitempty"`
+}
+
+// fixtureValue keeps the type of a driver value through JSON: the integers, strings, booleans and NULLs as
+// such, the other types as {"float": ...}, {"time": ...}, {"bytes": ...} or {"sha256": ...} objects.
+type fixtureValue struct {
+	value driver.Value
+}
+
+// hashedValue is the SHA-256 of a secret argument, stored as {"sha256": ...}.
+type hashedValue string
+
+func (v fixtureValue) MarshalJSON() ([]byte, error) {
+	switch value := v.value.(type) {
+	case nil, int64, bool, string:
+		return json.Marshal(value)
+	case float64:
+		return json.Marshal(map[string]float64{"float": value})
+	case time.Time:
+		return json.Marshal(map[string]string{"time": value.Format(time.RFC3339Nano)})
+	case []byte:
+		return json.Marshal(map[string]string{"bytes": base64.StdEncoding.EncodeToString(value)})
+	case hashedValue:
+		return json.Marshal(map[string]string{"sha256": string(value)})
+	default:
+		return nil, fmt.Errorf("unsupported fixture value %T", value)
+	}
+}
+
+func (v *fixtureValue) UnmarshalJSON(data []byte) error {
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var raw interface{}
+	if err := decoder.Decode(&raw); err != nil {
+		return err
+	}
+
+	switch value := raw.(type) {
+	case json.Number:
+		n, err := value.Int64()
+		v.value = n
+		return err
+	case map[string]interface{}:
+		for kind, encoded := range value {
+			switch kind {
+			case "float":
+				f, err := encoded.(json.Number).Float64()
+				v.value = f
+				return err
+			case "time":
+				t, err := time.Parse(time.RFC3339Nano, encoded.(string))
+				v.value = t
+				return err
+			case "bytes":
+				b, err := base64.StdEncoding.DecodeString(encoded.(string))
+				v.value = b
+				return err
+			case "sha256":
+				v.value = hashedValue(encoded.(string))
+				return nil
+			}
+		}
+		return fmt.Errorf("unsupported fixture value %s", data)
+	default:
+		v.value = value
+		return nil
+	}
+}
+
+func fixtureValues(values []driver.Value) []fixtureValue {
+	encoded := make([]fixtureValue, len(values))
+	for i, value := range values {
+		encoded[i] = fixtureValue{value}
+	}
+	return encoded
+}
+
+// fixtureArgs encodes the bind arguments, replacing the secret ones (see Redactor) with the hash of their value.
+func fixtureArgs(redactor *Redactor, query string, args []driver.NamedValue) []fixtureValue {
+	columns := boundColumns(query)
+	encoded := make([]fixtureValue, len(args))
+	for i, arg := range args {
+		value := arg.Value
+		if secret, ok := value.(SecretArg); ok {
+			value = secret.value
+		}
+		if redactor.isSecret(arg, columns) {
+			data, _ := fixtureValue{value}.MarshalJSON()
+			value = hashedValue(fmt.Sprintf("%x", sha256.Sum256(data)))
+		}
+		encoded[i] = fixtureValue{value}
+	}
+	return encoded
+}
+
+// portableErrors are restored as such on replay, the other errors by their message only.
+var portableErrors = []error{
+	sqlcommons.UniqueConstraintViolation, sqlcommons.IntegrityConstraintViolation, sqlcommons.ValueTooLargeForColumn,
+	sqlcommons.ValueLargerThanPrecision, sqlcommons.CannotSetNullColumn, sqlcommons.InvalidNumericValue,
+	sqlcommons.SubqueryReturnsMoreThanOneRow, sqlcommons.ConnectionClosed, sqlcommons.ConnectionFailed,
+	sqlerrors.QueryTimeout, sqlerrors.StatementNotAllowed, driver.ErrBadConn,
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	for _, portable := range portableErrors {
+		if errors.Is(err, portable) {
+			return portable.Error()
+		}
+	}
+	return err.Error()
+}
+
+func textError(text string) error {
+	if text == "" {
+		return nil
+	}
+	for _, portable := range portableErrors {
+		if portable.Error() == text {
+			return portable
+		}
+	}
+	return errors.New(text)
+}
+
+// fixtureRecorder rewrites the fixture file after each interaction, so it is complete whenever the test ends.
+type fixtureRecorder struct {
+	mu      sync.Mutex
+	path    string
+	fixture fixture
+}
+
+func (r *fixtureRecorder) record(it interaction) error {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fixture.Interactions = append(r.fixture.Interactions, it)
+	return r.save()
+}
+
+func (r *fixtureRecorder) save() error {
+	data, err := json.MarshalIndent(r.fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, data, 0644)
+}
+
+// fixturePlayer serves the interactions of a fixture in the order they were recorded.
+type fixturePlayer struct {
+	mu       sync.Mutex
+	fixture  fixture
+	redactor *Redactor
+	next     int
+}
+
+func loadFixture(path string) (fixture, error) {
+	var f fixture
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return f, err
+	}
+	return f, json.Unmarshal(data, &f)
+}
+
+// play returns the next interaction, failing with sqlerrors.UnexpectedStatement if it isn't the given one.
+func (p *fixturePlayer) play(op Operation, query string, args []driver.NamedValue) (interaction, error) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.next >= len(p.fixture.Interactions) {
+		return interaction{}, fmt.Errorf("%w: %s [%s], past the end of the fixture", sqlerrors.UnexpectedStatement, op, query)
+	}
+	expected := p.fixture.Interactions[p.next]
+	actualArgs, err := json.Marshal(fixtureArgs(p.redactor, query, args))
+	if err != nil {
+		return interaction{}, err
+	}
+	expectedArgs, _ := json.Marshal(expected.Args)
+	if expected.Args == nil {
+		expectedArgs = []byte("[]")
+	}
+	if expected.Operation != op || expected.Query != query || !bytes.Equal(actualArgs, expectedArgs) {
+		return interaction{}, fmt.Errorf("%w: %s [%s] %s, expected %s [%s] %s", sqlerrors.UnexpectedStatement,
+			op, query, actualArgs, expected.Operation, expected.Query, expectedArgs)
+	}
+	p.next++
+	return expected, nil
+}
+
+// fixtureResult is the result of a recorded execution.
+type fixtureResult struct {
+	lastInsertID *int64
+	rowsAffected *int64
+}
+
+func (r fixtureResult) LastInsertId() (int64, error) {
+	if r.lastInsertID == nil {
+		return 0, sqlcommons.OpNotSupported
+	}
+	return *r.lastInsertID, nil
+}
+
+func (r fixtureResult) RowsAffected() (int64, error) {
+	if r.rowsAffected == nil {
+		return 0, sqlcommons.OpNotSupported
+	}
+	return *r.rowsAffected, nil
+}
+
+// fixtureRows serves rows held in memory, failing with err once they are exhausted, if any.
+type fixtureRows struct {
+	columns []string
+	rows    [][]driver.Value
+	err     error
+	next    int
+}
+
+func (r *fixtureRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fixtureRows) Close() error {
+	return nil
+}
+
+func (r *fixtureRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		if r.err != nil {
+			return r.err
+		}
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
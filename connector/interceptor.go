@@ -4,48 +4,211 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/cdleo/go-commons/logger"
 	"github.com/cdleo/go-commons/sqlcommons"
 	proxy "github.com/cdleo/go-sql-proxy"
+	"github.com/cdleo/go-sqldb/fingerprint"
 )
 
-func registerProxy(name string, logger logger.Logger, translator sqlcommons.SQLAdapter, sqlDriver driver.Driver) {
+type Operation string
 
-	drivers := sql.Drivers()
-	for _, item := range drivers {
-		if item == name {
-			return
+const (
+	OpExec     Operation = "Exec"
+	OpQuery    Operation = "Query"
+	OpBegin    Operation = "Begin"
+	OpCommit   Operation = "Commit"
+	OpRollback Operation = "Rollback"
+)
+
+// QueryEvent describes a statement flowing through the interceptor. Query always holds the translated SQL,
+// and Fingerprint identifies its shape (see the fingerprint package).
+type QueryEvent struct {
+	Operation    Operation
+	Query        string
+	Fingerprint  string
+	Args         []driver.NamedValue
+	Start        time.Time
+	Duration     time.Duration
+	RowsAffected int64
+	Err          error
+	// Rejected tells whether a hook's Before rejected the statement, which never reached the driver
+	Rejected bool
+	redactor *Redactor
+}
+
+// RedactedQuery returns the statement with its sensitive literals masked, ready to be logged.
+func (e *QueryEvent) RedactedQuery() string {
+	return prettyQuery(e.redactor.RedactQuery(e.Query))
+}
+
+// RedactedArgs returns the bind arguments with the sensitive ones masked, ready to be logged.
+func (e *QueryEvent) RedactedArgs() []string {
+	return e.redactor.RedactArgs(e.Query, e.Args)
+}
+
+// Hook is the extension point of the interceptor. Before is called once the statement has been translated
+// and may reject it by returning an error; After is called when the driver returns.
+type Hook interface {
+	Before(ctx context.Context, event *QueryEvent) error
+	After(ctx context.Context, event *QueryEvent)
+}
+
+// Interceptable is implemented by the connectors whose driver runs through the interceptor.
+type Interceptable interface {
+	sqlcommons.SQLConnector
+	SetHooks(hooks ...Hook)
+	SetRedactor(redactor *Redactor)
+	SetTimeouts(timeouts Timeouts)
+}
+
+// dbBinder is implemented by the hooks that need to issue statements of their own once the DB is opened.
+type dbBinder interface {
+	bind(db *sql.DB, engine string)
+}
+
+// connectionWatcher is implemented by the hooks that watch over the connections opened (and pinged) by the pool.
+type connectionWatcher interface {
+	allowConnect(ctx context.Context) error
+	connected(err error)
+}
+
+type interception struct {
+	hooks    []Hook
+	redactor *Redactor
+	timeouts Timeouts
+}
+
+func (i *interception) SetHooks(hooks ...Hook) {
+	i.hooks = hooks
+}
+
+func (i *interception) SetRedactor(redactor *Redactor) {
+	i.redactor = redactor
+}
+
+func (i *interception) SetTimeouts(timeouts Timeouts) {
+	i.timeouts = timeouts
+}
+
+// activeRedactor returns the redactor set, or the default one.
+func (i *interception) activeRedactor() *Redactor {
+	if i.redactor == nil {
+		return NewRedactor()
+	}
+	return i.redactor
+}
+
+type dsnConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func newDSNConnector(sqlDriver driver.Driver, dsn string) (driver.Connector, error) {
+	if driverCtx, ok := sqlDriver.(driver.DriverContext); ok {
+		return driverCtx.OpenConnector(dsn)
+	}
+	return &dsnConnector{sqlDriver, dsn}, nil
+}
+
+func (c *dsnConnector) Connect(_ context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+func openProxy(engine string, logger logger.Logger, translator sqlcommons.SQLAdapter, sqlConnector driver.Connector, config interception) *sql.DB {
+
+	config.redactor = config.activeRedactor()
+	wrapped := &wrappedConnector{Connector: sqlConnector, engine: engine, timeouts: config.timeouts}
+	if inner, ok := sqlConnector.(*innerConnector); ok {
+		wrapped.keepSecrets = inner.keepSecrets
+	}
+
+	db := sql.OpenDB(proxy.NewConnector(wrapped, newInterceptor(logger, translator, config)))
+	for _, hook := range config.hooks {
+		if binder, ok := hook.(dbBinder); ok {
+			binder.bind(db, engine)
+		}
+	}
+	return db
+}
+
+func newInterceptor(logger logger.Logger, translator sqlcommons.SQLAdapter, config interception) *proxy.HooksContext {
+
+	var watchers []connectionWatcher
+	for _, hook := range config.hooks {
+		if watcher, ok := hook.(connectionWatcher); ok {
+			watchers = append(watchers, watcher)
 		}
 	}
 
-	sql.Register(name, proxy.NewProxyContext(sqlDriver, &proxy.HooksContext{
+	return &proxy.HooksContext{
+		PreOpen: func(c context.Context, _ string) (interface{}, error) {
+			for _, watcher := range watchers {
+				if err := watcher.allowConnect(c); err != nil {
+					return nil, err
+				}
+			}
+			return nil, nil
+		},
 		Open: func(_ context.Context, _ interface{}, conn *proxy.Conn) error {
 			logger.Qry("Open conn")
 			return nil
 		},
+		PostOpen: func(_ context.Context, _ interface{}, _ *proxy.Conn, err error) error {
+			for _, watcher := range watchers {
+				watcher.connected(err)
+			}
+			return nil
+		},
+		PrePing: func(c context.Context, _ *proxy.Conn) (interface{}, error) {
+			for _, watcher := range watchers {
+				if err := watcher.allowConnect(c); err != nil {
+					return nil, err
+				}
+			}
+			return nil, nil
+		},
+		PostPing: func(_ context.Context, _ interface{}, _ *proxy.Conn, err error) error {
+			for _, watcher := range watchers {
+				watcher.connected(err)
+			}
+			return nil
+		},
 		Close: func(_ context.Context, _ interface{}, conn *proxy.Conn) error {
 			logger.Qry("Close conn")
 			return nil
 		},
 
-		PreExec: func(_ context.Context, stmt *proxy.Stmt, _ []driver.NamedValue) (interface{}, error) {
-			stmt.QueryString = translator.Translate(stmt.QueryString)
-			return time.Now(), nil
+		PreExec: func(c context.Context, stmt *proxy.Stmt, args []driver.NamedValue) (interface{}, error) {
+			return beforeHooks(c, config, translator, OpExec, stmt, args)
 		},
-		PostExec: func(_ context.Context, ctx interface{}, stmt *proxy.Stmt, args []driver.NamedValue, _ driver.Result, _ error) error {
-			logger.Tracef("Exec: %s; args = %v (%s)", prettyQuery(stmt.QueryString), args, time.Since(ctx.(time.Time)))
+		PostExec: func(c context.Context, ctx interface{}, stmt *proxy.Stmt, args []driver.NamedValue, result driver.Result, err error) error {
+			event := ctx.(*QueryEvent)
+			if result != nil {
+				if rows, rowsErr := result.RowsAffected(); rowsErr == nil {
+					event.RowsAffected = rows
+				}
+			}
+			logger.Tracef("Exec: %s; args = %v (%s)", event.RedactedQuery(), event.RedactedArgs(), time.Since(event.Start))
+			afterHooks(c, config.hooks, event, err)
 			return nil
 		},
 
-		PreQuery: func(_ context.Context, stmt *proxy.Stmt, _ []driver.NamedValue) (interface{}, error) {
-			stmt.QueryString = translator.Translate(stmt.QueryString)
-			return time.Now(), nil
+		PreQuery: func(c context.Context, stmt *proxy.Stmt, args []driver.NamedValue) (interface{}, error) {
+			return beforeHooks(c, config, translator, OpQuery, stmt, args)
 		},
-		PostQuery: func(_ context.Context, ctx interface{}, stmt *proxy.Stmt, args []driver.NamedValue, _ driver.Rows, _ error) error {
-			logger.Tracef("Query: %s; args = %v (%s)", prettyQuery(stmt.QueryString), args, time.Since(ctx.(time.Time)))
+		PostQuery: func(c context.Context, ctx interface{}, stmt *proxy.Stmt, args []driver.NamedValue, _ driver.Rows, err error) error {
+			event := ctx.(*QueryEvent)
+			logger.Tracef("Query: %s; args = %v (%s)", event.RedactedQuery(), event.RedactedArgs(), time.Since(event.Start))
+			afterHooks(c, config.hooks, event, err)
 			return nil
 		},
 
@@ -65,7 +228,82 @@ func registerProxy(name string, logger logger.Logger, translator sqlcommons.SQLA
 		OnError: func(ctx interface{}, err error) error {
 			return translator.ErrorHandler(err)
 		},
-	}))
+	}
+}
+
+type nativeSQLCtxKey struct{}
+
+// WithNativeSQL returns a copy of ctx whose statements are already written in the engine's own syntax,
+// so they reach the hooks and the driver as is, rather than translated by the adapter.
+func WithNativeSQL(ctx context.Context) context.Context {
+	return context.WithValue(ctx, nativeSQLCtxKey{}, true)
+}
+
+func isNativeSQL(ctx context.Context) bool {
+	native, _ := ctx.Value(nativeSQLCtxKey{}).(bool)
+	return native
+}
+
+func beforeHooks(c context.Context, config interception, translator sqlcommons.SQLAdapter, op Operation, stmt *proxy.Stmt, args []driver.NamedValue) (*QueryEvent, error) {
+
+	if !isNativeSQL(c) {
+		stmt.QueryString = translator.Translate(stmt.QueryString)
+	}
+	event := &QueryEvent{
+		Operation:    op,
+		Query:        stmt.QueryString,
+		Fingerprint:  fingerprint.Fingerprint(stmt.QueryString),
+		Args:         args,
+		RowsAffected: -1,
+		redactor:     config.redactor,
+	}
+	for _, hook := range config.hooks {
+		if err := hook.Before(c, event); err != nil {
+			event.Start = time.Now()
+			event.Rejected = true
+			return event, translator.ErrorHandler(err)
+		}
+	}
+	stmt.QueryString = event.Query
+	event.Start = time.Now()
+	return event, nil
+}
+
+func afterHooks(c context.Context, hooks []Hook, event *QueryEvent, err error) {
+
+	event.Duration = time.Since(event.Start)
+	event.Err = err
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i].After(c, event)
+	}
+}
+
+// callerLocation returns the file:line of the first frame outside database/sql, the proxy driver and this module
+// (but its tests).
+func callerLocation() string {
+
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !isInternalFrame(frame.Function) || strings.HasSuffix(frame.File, "_test.go") {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+func isInternalFrame(function string) bool {
+	for _, prefix := range []string{"runtime.", "database/sql", "github.com/cdleo/go-sql-proxy", "github.com/cdleo/go-sqldb.",
+		"github.com/cdleo/go-sqldb/connector.", "github.com/cdleo/go-sqldb/sqlbuilder."} {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 func prettyQuery(query string) string {
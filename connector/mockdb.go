@@ -3,38 +3,303 @@ package connector
 import (
 	"database/sql"
 	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/cdleo/go-commons/logger"
 	"github.com/cdleo/go-commons/sqlcommons"
 )
 
+// MockSQLConnector extends the mock connector of sqlcommons with richer expectations. The expectations
+// are met in order by default.
+type MockSQLConnector interface {
+	sqlcommons.MockSQLConnector
+
+	PatchRow(query string, columns []MockColumn, err error, args ...driver.Value)
+	PatchSequence(sequenceName string, values ...int64)
+	PatchSequenceError(sequenceName string, err error)
+
+	WithEngine(engine string) MockSQLConnector
+
+	ExpectExec(query QueryMatcher) *MockExec
+	ExpectQuery(query QueryMatcher) *MockQuery
+	ExpectPrepare(query QueryMatcher) *MockPrepare
+	ExpectClose(err error)
+	MatchExpectationsInOrder(inOrder bool)
+	ExpectationsWereMet() error
+}
+
+// QueryMatcher tells whether a statement is the expected one. String describes the expectation, and
+// tells the matchers apart, so two different matchers must not share it.
+type QueryMatcher interface {
+	Match(query string) error
+	String() string
+}
+
+// ExactQuery matches the statement equal to the given one, regardless of the whitespace.
+type ExactQuery string
+
+func (q ExactQuery) Match(query string) error {
+	return sqlmock.QueryMatcherEqual.Match(string(q), query)
+}
+
+func (q ExactQuery) String() string {
+	return string(q)
+}
+
+type regexpQuery struct {
+	expr *regexp.Regexp
+}
+
+// RegexpQuery matches the statements matching the regular expression.
+func RegexpQuery(expr string) QueryMatcher {
+	return regexpQuery{regexp.MustCompile(expr)}
+}
+
+func (q regexpQuery) Match(query string) error {
+	if !q.expr.MatchString(query) {
+		return fmt.Errorf(`actual sql: "%s" does not match the expected regexp "%s"`, query, q.expr)
+	}
+	return nil
+}
+
+func (q regexpQuery) String() string {
+	return "regexp:" + q.expr.String()
+}
+
+type funcQuery struct {
+	description string
+	match       func(query string) bool
+}
+
+// QueryMatching matches the statements accepted by the function, described as given.
+func QueryMatching(description string, match func(query string) bool) QueryMatcher {
+	return funcQuery{description, match}
+}
+
+func (q funcQuery) Match(query string) error {
+	if !q.match(query) {
+		return fmt.Errorf(`actual sql: "%s" does not match the expected %s`, query, q.description)
+	}
+	return nil
+}
+
+func (q funcQuery) String() string {
+	return "func:" + q.description
+}
+
+type argMatcher func(value driver.Value) bool
+
+func (m argMatcher) Match(value driver.Value) bool {
+	return m(value)
+}
+
+// AnyArg matches any argument.
+func AnyArg() driver.Value {
+	return sqlmock.AnyArg()
+}
+
+// ArgOfType matches the arguments of the same type as the sample, once converted to a driver value (e.g. int64 for the integers).
+func ArgOfType(sample interface{}) driver.Value {
+	converted, err := driver.DefaultParameterConverter.ConvertValue(sample)
+	if err != nil {
+		converted = sample
+	}
+	sampleType := reflect.TypeOf(converted)
+	return argMatcher(func(value driver.Value) bool {
+		return reflect.TypeOf(value) == sampleType
+	})
+}
+
+// ArgMatching matches the arguments accepted by the function.
+func ArgMatching(match func(value driver.Value) bool) driver.Value {
+	return argMatcher(match)
+}
+
+// MockColumn is a column of a mocked row, along with its value.
+type MockColumn struct {
+	Name  string
+	Value driver.Value
+}
+
+func Column(name string, value driver.Value) MockColumn {
+	return MockColumn{name, value}
+}
+
+// MockExec is the expectation of a statement execution. Its arguments are matched by equality, unless they
+// are matchers (see AnyArg, ArgOfType and ArgMatching).
+type MockExec struct {
+	expected *sqlmock.ExpectedExec
+}
+
+func (e *MockExec) WithArgs(args ...driver.Value) *MockExec {
+	e.expected.WithArgs(args...)
+	return e
+}
+
+func (e *MockExec) WillReturnResult(lastInsertID int64, rowsAffected int64) *MockExec {
+	e.expected.WillReturnResult(sqlmock.NewResult(lastInsertID, rowsAffected))
+	return e
+}
+
+func (e *MockExec) WillReturnError(err error) *MockExec {
+	e.expected.WillReturnError(err)
+	return e
+}
+
+func (e *MockExec) WillDelayFor(duration time.Duration) *MockExec {
+	e.expected.WillDelayFor(duration)
+	return e
+}
+
+// MockQuery is the expectation of a query, whose arguments are matched as MockExec does.
+type MockQuery struct {
+	expected *sqlmock.ExpectedQuery
+}
+
+func (e *MockQuery) WithArgs(args ...driver.Value) *MockQuery {
+	e.expected.WithArgs(args...)
+	return e
+}
+
+// WillReturnRows returns the rows, with a value per column each.
+func (e *MockQuery) WillReturnRows(columns []string, rows ...[]driver.Value) *MockQuery {
+	e.expected.WillReturnRows(sqlmock.NewRows(columns).AddRows(rows...))
+	return e
+}
+
+func (e *MockQuery) WillReturnError(err error) *MockQuery {
+	e.expected.WillReturnError(err)
+	return e
+}
+
+func (e *MockQuery) WillDelayFor(duration time.Duration) *MockQuery {
+	e.expected.WillDelayFor(duration)
+	return e
+}
+
+// MockPrepare is the expectation of a statement preparation, followed by the expectations of its executions.
+type MockPrepare struct {
+	expected *sqlmock.ExpectedPrepare
+}
+
+func (e *MockPrepare) WillReturnError(err error) *MockPrepare {
+	e.expected.WillReturnError(err)
+	return e
+}
+
+func (e *MockPrepare) WillBeClosed() *MockPrepare {
+	e.expected.WillBeClosed()
+	return e
+}
+
+func (e *MockPrepare) WillDelayFor(duration time.Duration) *MockPrepare {
+	e.expected.WillDelayFor(duration)
+	return e
+}
+
+func (e *MockPrepare) ExpectExec() *MockExec {
+	return &MockExec{e.expected.ExpectExec()}
+}
+
+func (e *MockPrepare) ExpectQuery() *MockQuery {
+	return &MockQuery{e.expected.ExpectQuery()}
+}
+
 type mockDBSqlConn struct {
-	initOk bool
-	mock   sqlmock.Sqlmock
+	interception
+	initOk   bool
+	engine   string
+	mock     sqlmock.Sqlmock
+	mu       sync.Mutex
+	matchers map[string]QueryMatcher
 }
 
-func NewMockSQLConnector(initOk bool) sqlcommons.MockSQLConnector {
+func NewMockSQLConnector(initOk bool) MockSQLConnector {
 
 	return &mockDBSqlConn{
-		initOk,
-		nil,
+		initOk:   initOk,
+		engine:   mockEngine,
+		matchers: make(map[string]QueryMatcher),
 	}
 }
 
+const mockEngine = "MockDB"
+
+var mockDSNCount int64
+
+// Open runs the sqlmock driver through the interceptor, as the other connectors do with theirs, so the
+// expectations are met by the translated statements and the mocked errors are mapped by the adapter.
 func (s *mockDBSqlConn) Open(logger logger.Logger, translator sqlcommons.SQLAdapter) (*sql.DB, error) {
 
-	if s.initOk {
-		db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
-		s.mock = mock
-		return db, err
-	} else {
+	if !s.initOk {
 		return nil, sqlcommons.ConnectionFailed
 	}
+
+	// The DB of sqlmock is kept open, otherwise its connection would be discarded along with it
+	dsn := fmt.Sprintf("mockdb_%d", atomic.AddInt64(&mockDSNCount, 1))
+	mockDB, mock, err := sqlmock.NewWithDSN(dsn, sqlmock.QueryMatcherOption(sqlmock.QueryMatcherFunc(s.matchQuery)))
+	if err != nil {
+		return nil, err
+	}
+	s.mock = mock
+
+	sqlConnector, err := newDSNConnector(mockDB.Driver(), dsn)
+	if err != nil {
+		return nil, err
+	}
+	return openProxy(s.engine, logger, translator, sqlConnector, s.interception), nil
+}
+
+// matchQuery matches a statement with the matcher registered for the expectation, or by equality.
+func (s *mockDBSqlConn) matchQuery(expectedSQL string, actualSQL string) error {
+
+	s.mu.Lock()
+	matcher, ok := s.matchers[expectedSQL]
+	s.mu.Unlock()
+	if !ok {
+		return sqlmock.QueryMatcherEqual.Match(expectedSQL, actualSQL)
+	}
+	return matcher.Match(actualSQL)
+}
+
+func (s *mockDBSqlConn) register(query QueryMatcher) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.matchers[query.String()] = query
+	return query.String()
+}
+
+// WithEngine makes the mock behave as the connector of the engine (Oracle, PostgreSQL or SQLite3) does, issuing
+// the same statements (e.g. to get the next value of a sequence) and telling the engine to the proxy.
+func (s *mockDBSqlConn) WithEngine(engine string) MockSQLConnector {
+	s.engine = engine
+	return s
+}
+
+func (s *mockDBSqlConn) Engine() string {
+	return s.engine
 }
 
 func (s *mockDBSqlConn) GetNextSequenceQuery(sequenceName string) string {
-	return sequenceName
+	return sequenceQuery(s.engine, sequenceName)
+}
+
+// PatchSequence patches the next values of the sequence, one per call.
+func (s *mockDBSqlConn) PatchSequence(sequenceName string, values ...int64) {
+	for _, value := range values {
+		s.PatchRow(s.GetNextSequenceQuery(sequenceName), []MockColumn{Column("nextval", value)}, nil)
+	}
+}
+
+func (s *mockDBSqlConn) PatchSequenceError(sequenceName string, err error) {
+	s.PatchRow(s.GetNextSequenceQuery(sequenceName), nil, err)
 }
 
 func (s *mockDBSqlConn) PatchBegin(err error) {
@@ -84,15 +349,55 @@ func (s *mockDBSqlConn) PatchQuery(query string, columns []string, values []driv
 	}
 }
 
+// PatchQueryRow patches a query returning a single row of strings, whose columns are sorted by name.
 func (s *mockDBSqlConn) PatchQueryRow(query string, result map[string]string, err error) {
-	var keys []string
-	var values []interface{}
-	for key, value := range result {
-		keys = append(keys, key)
-		values = append(values, value)
+	names := make([]string, 0, len(result))
+	for name := range result {
+		names = append(names, name)
 	}
-	rows := sqlmock.NewRows(keys).
-		AddRow(values)
+	sort.Strings(names)
+
+	columns := make([]MockColumn, len(names))
+	for i, name := range names {
+		columns[i] = Column(name, result[name])
+	}
+	s.PatchRow(query, columns, err)
+}
+
+// PatchRow patches a query returning a single row with the given columns, in order, or failing with err.
+func (s *mockDBSqlConn) PatchRow(query string, columns []MockColumn, err error, args ...driver.Value) {
+	names := make([]string, len(columns))
+	values := make([]driver.Value, len(columns))
+	for i, column := range columns {
+		names[i], values[i] = column.Name, column.Value
+	}
+	s.PatchQuery(query, names, values, err, args...)
+}
+
+func (s *mockDBSqlConn) ExpectExec(query QueryMatcher) *MockExec {
+	return &MockExec{s.mock.ExpectExec(s.register(query)).WillReturnResult(sqlmock.NewResult(0, 0))}
+}
+
+func (s *mockDBSqlConn) ExpectQuery(query QueryMatcher) *MockQuery {
+	return &MockQuery{s.mock.ExpectQuery(s.register(query))}
+}
+
+func (s *mockDBSqlConn) ExpectPrepare(query QueryMatcher) *MockPrepare {
+	return &MockPrepare{s.mock.ExpectPrepare(s.register(query))}
+}
+
+func (s *mockDBSqlConn) ExpectClose(err error) {
+	expectClose := s.mock.ExpectClose()
+	if err != nil {
+		expectClose.WillReturnError(err)
+	}
+}
+
+// MatchExpectationsInOrder sets whether the expectations must be met in the order they were set, or in any order.
+func (s *mockDBSqlConn) MatchExpectationsInOrder(inOrder bool) {
+	s.mock.MatchExpectationsInOrder(inOrder)
+}
 
-	s.mock.ExpectQuery(query).WillReturnRows(rows).WillReturnError(err)
+func (s *mockDBSqlConn) ExpectationsWereMet() error {
+	return s.mock.ExpectationsWereMet()
 }
@@ -0,0 +1,201 @@
+package connector
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cdleo/go-commons/logger"
+	"github.com/cdleo/go-commons/sqlcommons"
+	"github.com/cdleo/go-sqldb/adapter"
+	"github.com/cdleo/go-sqldb/sqlerrors"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func openMock(t *testing.T) (MockSQLConnector, *sql.DB) {
+	mock := NewMockSQLConnector(true)
+	db, err := mock.Open(logger.NewNoLogLogger(), adapter.NewNoopAdapter())
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return mock, db
+}
+
+func Test_MockSQLConnector_Expectations(t *testing.T) {
+	// Setup
+	mock, db := openMock(t)
+	mock.ExpectExec(ExactQuery("INSERT INTO customers (name, age) VALUES (:1, :2)")).
+		WithArgs("Ana", ArgOfType(0)).
+		WillReturnResult(7, 1)
+	mock.ExpectQuery(RegexpQuery(`^SELECT .* FROM customers`)).
+		WithArgs(ArgMatching(func(value driver.Value) bool { return value.(int64) > 18 })).
+		WillReturnRows([]string{"id", "name"}, []driver.Value{1, "Juan"}, []driver.Value{7, "Ana"})
+	mock.ExpectExec(QueryMatching("delete", func(query string) bool { return query[:6] == "DELETE" })).
+		WithArgs(AnyArg()).
+		WillReturnError(errors.New("locked"))
+
+	// Exec
+	result, err := db.Exec("INSERT INTO customers (name, age)\n\tVALUES (:1, :2)", "Ana", 30)
+	require.NoError(t, err)
+	id, _ := result.LastInsertId()
+	rows, _ := result.RowsAffected()
+	require.Equal(t, int64(7), id)
+	require.Equal(t, int64(1), rows)
+
+	cursor, err := db.Query("SELECT id, name FROM customers WHERE age > :1", 20)
+	require.NoError(t, err)
+	var names []string
+	for cursor.Next() {
+		var id int64
+		var name string
+		require.NoError(t, cursor.Scan(&id, &name))
+		names = append(names, name)
+	}
+	require.NoError(t, cursor.Close())
+	require.Equal(t, []string{"Juan", "Ana"}, names)
+
+	require.Error(t, mock.ExpectationsWereMet())
+	_, err = db.Exec("DELETE FROM customers WHERE id = :1", 7)
+	require.EqualError(t, err, "locked")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_MockSQLConnector_UnorderedAndDelayed(t *testing.T) {
+	// Setup
+	mock, db := openMock(t)
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectExec(ExactQuery("UPDATE a SET x = 1"))
+	mock.ExpectExec(ExactQuery("UPDATE b SET x = 1")).WillDelayFor(50 * time.Millisecond)
+
+	// Exec
+	_, err := db.Exec("UPDATE b SET x = 1")
+	require.NoError(t, err)
+	_, err = db.Exec("UPDATE a SET x = 1")
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	mock.ExpectExec(ExactQuery("UPDATE b SET x = 1")).WillDelayFor(time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = db.ExecContext(ctx, "UPDATE b SET x = 1")
+	require.ErrorIs(t, err, sqlerrors.QueryTimeout)
+}
+
+func Test_MockSQLConnector_PrepareAndClose(t *testing.T) {
+	// Setup
+	mock := NewMockSQLConnector(true)
+	db, err := mock.Open(logger.NewNoLogLogger(), adapter.NewNoopAdapter())
+	require.NoError(t, err)
+
+	prepare := mock.ExpectPrepare(ExactQuery("UPDATE customers SET age = :1 WHERE id = :2")).WillBeClosed()
+	prepare.ExpectExec().WithArgs(31, 1).WillReturnResult(0, 1)
+	prepare.ExpectExec().WithArgs(32, 2).WillReturnResult(0, 1)
+	mock.ExpectClose(nil)
+
+	// Exec
+	statement, err := db.Prepare("UPDATE customers SET age = :1 WHERE id = :2")
+	require.NoError(t, err)
+	_, err = statement.Exec(31, 1)
+	require.NoError(t, err)
+	_, err = statement.Exec(32, 2)
+	require.NoError(t, err)
+	require.NoError(t, statement.Close())
+	require.NoError(t, db.Close())
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_MockSQLConnector_PatchQueryRow(t *testing.T) {
+	// Setup
+	mock, db := openMock(t)
+	mock.PatchQueryRow("SELECT name, city, age FROM customers", map[string]string{"name": "Juan", "city": "Rosario", "age": "40"}, nil)
+	mock.PatchQueryRow("SELECT name FROM customers", nil, sql.ErrConnDone)
+
+	// Exec
+	rows, err := db.Query("SELECT name, city, age FROM customers")
+	require.NoError(t, err)
+	columns, err := rows.Columns()
+	require.NoError(t, err)
+	require.Equal(t, []string{"age", "city", "name"}, columns)
+
+	require.True(t, rows.Next())
+	var age, city, name string
+	require.NoError(t, rows.Scan(&age, &city, &name))
+	require.Equal(t, []string{"40", "Rosario", "Juan"}, []string{age, city, name})
+	require.False(t, rows.Next())
+	require.NoError(t, rows.Close())
+
+	_, err = db.Query("SELECT name FROM customers")
+	require.ErrorIs(t, err, sql.ErrConnDone)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_MockSQLConnector_PatchRow(t *testing.T) {
+	// Setup
+	mock, db := openMock(t)
+	updated := time.Date(2024, 2, 29, 13, 45, 0, 0, time.UTC)
+	mock.PatchRow("SELECT * FROM customers WHERE id = :1", []MockColumn{
+		Column("id", int64(1)),
+		Column("name", "Juan"),
+		Column("age", nil),
+		Column("active", true),
+		Column("updatetime", updated),
+	}, nil, 1)
+
+	// Exec
+	var id int64
+	var name string
+	var age sql.NullInt64
+	var active bool
+	var updatetime time.Time
+	err := db.QueryRow("SELECT * FROM customers WHERE id = :1", 1).Scan(&id, &name, &age, &active, &updatetime)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), id)
+	require.Equal(t, "Juan", name)
+	require.False(t, age.Valid)
+	require.True(t, active)
+	require.Equal(t, updated, updatetime)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_MockSQLConnector_RunsThroughTheProxy(t *testing.T) {
+	// Setup
+	recorder := &recordingHook{}
+	mock := NewMockSQLConnector(true)
+	mock.(Interceptable).SetHooks(recorder)
+	db, err := mock.Open(logger.NewNoLogLogger(), adapter.NewPostgresAdapter("Oracle"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(ExactQuery("INSERT INTO customers (name) VALUES ($1)")).
+		WithArgs("Juan").
+		WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectQuery(ExactQuery("SELECT name FROM customers WHERE id = $1")).
+		WithArgs(1).
+		WillReturnRows([]string{"name"}, []driver.Value{"Juan"})
+
+	// Exec
+	_, err = db.Exec("INSERT INTO customers (name) VALUES (:1)", "Juan")
+	require.ErrorIs(t, err, sqlcommons.UniqueConstraintViolation)
+
+	var name string
+	require.NoError(t, db.QueryRow("SELECT name FROM customers WHERE id = :1", 1).Scan(&name))
+	require.Equal(t, "Juan", name)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Equal(t, []string{"INSERT INTO customers (name) VALUES ($1)", "SELECT name FROM customers WHERE id = $1"}, recorder.queries)
+}
+
+type recordingHook struct {
+	queries []string
+}
+
+func (h *recordingHook) Before(_ context.Context, event *QueryEvent) error {
+	h.queries = append(h.queries, event.Query)
+	return nil
+}
+
+func (h *recordingHook) After(_ context.Context, _ *QueryEvent) {}
@@ -1,23 +1,21 @@
 package connector
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"time"
 
-	"github.com/cdleo/go-commons/logger"
 	"github.com/cdleo/go-commons/sqlcommons"
-	"github.com/godror/godror"
-	"github.com/godror/godror/dsn"
 )
 
 type oracleConn struct {
+	interception
 	connString string
 	user       string
 	password   string
 }
 
-const oracleProxyName = "godror-proxy"
+const oracleEngine = "Oracle"
 
 func NewOracleSqlConnector(host string, port int, user string, password string, database string) sqlcommons.SQLConnector {
 
@@ -37,20 +35,14 @@ func NewOracleTNSSqlConnector(tnsName string, user string, password string) sqlc
 	}
 }
 
-func (s *oracleConn) Open(logger logger.Logger, translator sqlcommons.SQLAdapter) (*sql.DB, error) {
-
-	registerProxy(oracleProxyName, logger, translator, godror.NewConnector(dsn.ConnectionParams{}).Driver())
-
-	var connParams godror.ConnectionParams
-	connParams.ConnectString = s.connString
-	connParams.Username = s.user
-	connParams.Password = godror.NewPassword(s.password)
-	connParams.Timezone = time.Local
-	connParams.StandaloneConnection = true
+func (s *oracleConn) GetNextSequenceQuery(sequenceName string) string {
+	return fmt.Sprintf("SELECT %s.NEXTVAL FROM DUAL", sequenceName)
+}
 
-	return sql.Open(oracleProxyName, connParams.StringWithPassword())
+func (s *oracleConn) Engine() string {
+	return oracleEngine
 }
 
-func (s *oracleConn) GetNextSequenceQuery(sequenceName string) string {
-	return fmt.Sprintf("SELECT %s.NEXTVAL FROM DUAL", sequenceName)
+func (s *oracleConn) BulkInsert(ctx context.Context, db *sql.DB, translator sqlcommons.SQLAdapter, table string, columns []string, rows [][]interface{}) (int64, error) {
+	return arrayInsert(table, columns).insert(ctx, db, rows)
 }
@@ -0,0 +1,39 @@
+//go:build cgo
+
+package connector
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/cdleo/go-commons/logger"
+	"github.com/cdleo/go-commons/sqlcommons"
+	"github.com/godror/godror"
+	"github.com/godror/godror/dsn"
+)
+
+func (s *oracleConn) Open(logger logger.Logger, translator sqlcommons.SQLAdapter) (*sql.DB, error) {
+
+	var connParams godror.ConnectionParams
+	connParams.ConnectString = s.connString
+	connParams.Username = s.user
+	connParams.Password = godror.NewPassword(s.password)
+	connParams.Timezone = time.Local
+	connParams.StandaloneConnection = true
+
+	sqlConnector, err := newDSNConnector(godror.NewConnector(dsn.ConnectionParams{}).Driver(), connParams.StringWithPassword())
+	if err != nil {
+		return nil, err
+	}
+
+	// godror pushes the deadline of each statement's context as the call timeout (OCI_ATTR_CALL_TIMEOUT)
+	return openProxy(oracleEngine, logger, translator, sqlConnector, s.interception), nil
+}
+
+// oraErrorCode returns the code of a godror error.
+func oraErrorCode(err error) (int, bool) {
+	if oraError, ok := godror.AsOraErr(err); ok {
+		return oraError.Code(), true
+	}
+	return 0, false
+}
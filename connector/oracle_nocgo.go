@@ -0,0 +1,20 @@
+//go:build !cgo
+
+package connector
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/cdleo/go-commons/logger"
+	"github.com/cdleo/go-commons/sqlcommons"
+)
+
+// Open fails, as godror needs cgo.
+func (s *oracleConn) Open(logger logger.Logger, translator sqlcommons.SQLAdapter) (*sql.DB, error) {
+	return nil, errors.New("the Oracle connector requires cgo")
+}
+
+func oraErrorCode(err error) (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,73 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/cdleo/go-sqldb/sqlerrors"
+)
+
+// PolicyHook rejects the statements not allowed by the policy, once translated and before they reach
+// the driver, with sqlerrors.StatementNotAllowed.
+type PolicyHook struct {
+	denied   map[StatementKind]bool
+	patterns []*regexp.Regexp
+	filtered bool
+}
+
+func NewPolicyHook() *PolicyHook {
+	return &PolicyHook{denied: make(map[StatementKind]bool)}
+}
+
+// ReadOnly allows queries only: DML, DDL, PL/SQL blocks and any other statement are rejected.
+func (h *PolicyHook) ReadOnly() *PolicyHook {
+	return h.Deny(StatementDML, StatementDDL, StatementPLSQL, StatementOther)
+}
+
+func (h *PolicyHook) NoDDL() *PolicyHook {
+	return h.Deny(StatementDDL)
+}
+
+func (h *PolicyHook) Deny(kinds ...StatementKind) *PolicyHook {
+	for _, kind := range kinds {
+		h.denied[kind] = true
+	}
+	return h
+}
+
+// DenyPattern rejects the statements matching the regular expression.
+func (h *PolicyHook) DenyPattern(expr string) *PolicyHook {
+	h.patterns = append(h.patterns, regexp.MustCompile(expr))
+	return h
+}
+
+// DenyUnfilteredWrites rejects the UPDATE and DELETE statements without a WHERE clause.
+func (h *PolicyHook) DenyUnfilteredWrites() *PolicyHook {
+	h.filtered = true
+	return h
+}
+
+func (h *PolicyHook) Before(ctx context.Context, event *QueryEvent) error {
+
+	if isExplain(ctx) {
+		return nil
+	}
+
+	kind, command := ClassifyStatement(event.Query)
+	if h.denied[kind] {
+		return fmt.Errorf("%w: %s statement [%s]", sqlerrors.StatementNotAllowed, kind, command)
+	}
+	if h.filtered && (command == "UPDATE" || command == "DELETE") && !contains(topLevelWords(event.Query), "WHERE") {
+		return fmt.Errorf("%w: %s without WHERE clause", sqlerrors.StatementNotAllowed, command)
+	}
+	for _, pattern := range h.patterns {
+		if pattern.MatchString(event.Query) {
+			return fmt.Errorf("%w: statement matches [%s]", sqlerrors.StatementNotAllowed, pattern)
+		}
+	}
+	return nil
+}
+
+func (h *PolicyHook) After(_ context.Context, _ *QueryEvent) {
+}
@@ -1,10 +1,12 @@
 package connector
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"database/sql"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/cdleo/go-commons/logger"
@@ -14,16 +16,18 @@ import (
 )
 
 type pgSqlConn struct {
+	interception
 	host      string
 	port      int
 	user      string
 	password  string
 	database  string
 	sslMode   string
+	schema    string
 	TLSConfig *tls.Config
 }
 
-const postgresProxyName = "pgx-proxy"
+const postgresEngine = "PostgreSQL"
 
 func NewPostgreSqlConnector(host string, port int, user string, password string, database string) sqlcommons.SQLConnector {
 
@@ -66,26 +70,40 @@ func (s *pgSqlConn) WithTLS(sslMode string, allowInsecure bool, serverName strin
 	return nil
 }
 
-func (s *pgSqlConn) Open(logger logger.Logger, translator sqlcommons.SQLAdapter) (*sql.DB, error) {
+// WithSchema sets the schema the unqualified names resolve to (the search_path).
+func (s *pgSqlConn) WithSchema(schema string) {
+	s.schema = schema
+}
 
-	registerProxy(postgresProxyName, logger, translator, stdlib.GetDefaultDriver())
+func (s *pgSqlConn) Open(logger logger.Logger, translator sqlcommons.SQLAdapter) (*sql.DB, error) {
 
-	psqlConn := fmt.Sprintf("host=%v port=%v user=%v password=%v dbname=%v sslmode=%v", s.host, s.port, s.user, s.password, s.database, s.sslMode)
+	// The password is set on the parsed config, so it never takes part of a string that could be logged
+	psqlConn := fmt.Sprintf("host=%v port=%v user=%v dbname=%v sslmode=%v", s.host, s.port, s.user, s.database, s.sslMode)
 
 	config, err := pgx.ParseConfig(psqlConn)
 	if err != nil {
 		return nil, err
 	}
+	config.Password = s.password
 	config.TLSConfig = s.TLSConfig
-
-	dbURI := stdlib.RegisterConnConfig(config)
-	dbPool, err := sql.Open(postgresProxyName, dbURI)
-	if err != nil {
-		return nil, fmt.Errorf("sql.Open: %w", err)
+	if timeout := s.timeouts.statementTimeout(); timeout > 0 {
+		config.RuntimeParams["statement_timeout"] = strconv.FormatInt(timeout.Milliseconds(), 10)
+	}
+	if s.schema != "" {
+		config.RuntimeParams["search_path"] = s.schema
 	}
-	return dbPool, nil
+
+	return openProxy(postgresEngine, logger, translator, stdlib.GetConnector(*config), s.interception), nil
 }
 
 func (s *pgSqlConn) GetNextSequenceQuery(sequenceName string) string {
 	return fmt.Sprintf("SELECT nextval('%s')", strings.ToLower(sequenceName))
 }
+
+func (s *pgSqlConn) Engine() string {
+	return postgresEngine
+}
+
+func (s *pgSqlConn) BulkInsert(ctx context.Context, db *sql.DB, translator sqlcommons.SQLAdapter, table string, columns []string, rows [][]interface{}) (int64, error) {
+	return copyFrom(ctx, db, translator, table, columns, rows)
+}
@@ -0,0 +1,230 @@
+package connector
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const redactedMask = "***"
+
+const defaultSensitiveNames = `(?i)pass(word|wd)?|pwd|secret|token|api_?key|credential|ssn|card_?number|cvv`
+
+const defaultSensitiveLiterals = `(?i)(pass(word|wd)?|pwd|secret|token|api_?key)\s*(=|:=)\s*'(''|[^'])*'`
+
+// SecretArg wraps a bind argument whose value must never be logged.
+type SecretArg struct {
+	value interface{}
+}
+
+func NewSecretArg(value interface{}) SecretArg {
+	return SecretArg{value}
+}
+
+func (s SecretArg) Value() (driver.Value, error) {
+	return driver.DefaultParameterConverter.ConvertValue(s.value)
+}
+
+func (s SecretArg) String() string {
+	return redactedMask
+}
+
+func (s SecretArg) GoString() string {
+	return redactedMask
+}
+
+// Redactor masks the bind arguments and the sensitive SQL literals before they reach the logs.
+// Arguments are masked unless their values are asked for, with WithArgValues (any argument) or
+// WithLoggedColumns (the ones bound to the given columns). Even then, they are masked when wrapped with
+// NewSecretArg or when their parameter name (or the column they are bound to) matches any of the name
+// patterns. Literals matching any of the literal patterns are masked inside the SQL text.
+type Redactor struct {
+	names     []*regexp.Regexp
+	literals  []*regexp.Regexp
+	noArgs    bool
+	allValues bool
+	logged    []*regexp.Regexp
+}
+
+// NewRedactor returns a redactor with the default patterns for passwords, secrets, tokens and card data.
+func NewRedactor() *Redactor {
+	return &Redactor{
+		names:    []*regexp.Regexp{regexp.MustCompile(defaultSensitiveNames)},
+		literals: []*regexp.Regexp{regexp.MustCompile(defaultSensitiveLiterals)},
+	}
+}
+
+func (r *Redactor) WithNamePattern(expr string) *Redactor {
+	r.names = append(r.names, regexp.MustCompile(expr))
+	return r
+}
+
+func (r *Redactor) WithLiteralPattern(expr string) *Redactor {
+	r.literals = append(r.literals, regexp.MustCompile(expr))
+	return r
+}
+
+// WithArgValues logs the values of the bind arguments, except the sensitive ones.
+func (r *Redactor) WithArgValues() *Redactor {
+	r.allValues = true
+	return r
+}
+
+// WithLoggedColumns logs the values of the bind arguments whose parameter name (or the column they are bound to)
+// matches the pattern, except the sensitive ones.
+func (r *Redactor) WithLoggedColumns(expr string) *Redactor {
+	r.logged = append(r.logged, regexp.MustCompile(expr))
+	return r
+}
+
+// WithoutArgs disables the logging of bind arguments altogether.
+func (r *Redactor) WithoutArgs() *Redactor {
+	r.noArgs = true
+	return r
+}
+
+func (r *Redactor) RedactQuery(query string) string {
+	for _, literal := range r.literals {
+		query = literal.ReplaceAllStringFunc(query, func(m string) string {
+			if idx := strings.Index(m, "'"); idx >= 0 {
+				return m[:idx] + "'" + redactedMask + "'"
+			}
+			return redactedMask
+		})
+	}
+	return query
+}
+
+func (r *Redactor) RedactArgs(query string, args []driver.NamedValue) []string {
+
+	if r.noArgs {
+		return []string{redactedMask}
+	}
+
+	columns := boundColumns(query)
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		if r.isSecret(arg, columns) || !r.isLogged(argName(arg, columns)) {
+			redacted[i] = redactedMask
+		} else {
+			redacted[i] = fmt.Sprintf("%v", arg.Value)
+		}
+	}
+	return redacted
+}
+
+// isSecret tells whether the argument is wrapped with NewSecretArg, or named after a sensitive name.
+func (r *Redactor) isSecret(arg driver.NamedValue, columns map[int]string) bool {
+	if _, ok := arg.Value.(SecretArg); ok {
+		return true
+	}
+	return matchesAny(r.names, argName(arg, columns))
+}
+
+func (r *Redactor) isLogged(name string) bool {
+	return r.allValues || matchesAny(r.logged, name)
+}
+
+// argName returns the parameter name of the argument, or else the column it is bound to.
+func argName(arg driver.NamedValue, columns map[int]string) string {
+	if arg.Name != "" {
+		return arg.Name
+	}
+	return columns[arg.Ordinal]
+}
+
+func matchesAny(patterns []*regexp.Regexp, name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		if pattern.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	insertColumnsRegExp = regexp.MustCompile(`(?is)INSERT\s+INTO\s+[^\s(]+\s*\(([^)]*)\)\s*VALUES\s*\(([^)]*)\)`)
+	comparisonRegExp    = regexp.MustCompile(`(?i)([A-Za-z_][\w$#.]*)\s*(=|<>|!=|<=|>=|<|>|\sLIKE\s)\s*(:\w+|\$\d+|\?)`)
+	placeholderRegExp   = regexp.MustCompile(`:\w+|\$\d+|\?`)
+	leadingPlaceholder  = regexp.MustCompile(`^(:\w+|\$\d+|\?)`)
+)
+
+// boundColumns maps the ordinal of each placeholder to the column it is bound to, as far as it can be
+// told from "INSERT INTO t (cols) VALUES (...)" lists and "column <op> placeholder" comparisons.
+func boundColumns(query string) map[int]string {
+
+	ordinals := placeholderOrdinals(query)
+	columns := make(map[int]string)
+
+	for _, m := range insertColumnsRegExp.FindAllStringSubmatchIndex(query, -1) {
+		names := strings.Split(query[m[2]:m[3]], ",")
+		values := strings.Split(query[m[4]:m[5]], ",")
+		offset := m[4]
+		for i, value := range values {
+			if i < len(names) {
+				if pos := placeholderRegExp.FindStringIndex(value); pos != nil {
+					columns[ordinals[offset+pos[0]]] = columnName(names[i])
+				}
+			}
+			offset += len(value) + 1
+		}
+	}
+
+	for _, m := range comparisonRegExp.FindAllStringSubmatchIndex(query, -1) {
+		columns[ordinals[m[6]]] = columnName(query[m[2]:m[3]])
+	}
+	return columns
+}
+
+// placeholderOrdinals maps the position of each placeholder outside string literals to its ordinal.
+func placeholderOrdinals(query string) map[int]int {
+
+	ordinals := make(map[int]int)
+	sequence := 0
+	named := make(map[string]int)
+	inLiteral := false
+	for i := 0; i < len(query); i++ {
+		if query[i] == '\'' {
+			inLiteral = !inLiteral
+			continue
+		}
+		if inLiteral || (query[i] != ':' && query[i] != '$' && query[i] != '?') {
+			continue
+		}
+		loc := leadingPlaceholder.FindStringIndex(query[i:])
+		if loc == nil {
+			continue
+		}
+		placeholder := query[i : i+loc[1]]
+		switch {
+		case placeholder == "?":
+			sequence++
+			ordinals[i] = sequence
+		default:
+			if n, err := strconv.Atoi(placeholder[1:]); err == nil {
+				ordinals[i] = n
+			} else {
+				if _, ok := named[placeholder]; !ok {
+					sequence++
+					named[placeholder] = sequence
+				}
+				ordinals[i] = named[placeholder]
+			}
+		}
+		i += loc[1] - 1
+	}
+	return ordinals
+}
+
+func columnName(expr string) string {
+	name := strings.Trim(strings.TrimSpace(expr), `"`+"`")
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		name = name[idx+1:]
+	}
+	return name
+}
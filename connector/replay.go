@@ -0,0 +1,405 @@
+package connector
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/cdleo/go-commons/logger"
+	"github.com/cdleo/go-commons/sqlcommons"
+)
+
+type recordingConn struct {
+	interception
+	inner sqlcommons.SQLConnector
+	path  string
+}
+
+// NewRecordingConnector runs the statements on the inner connector, writing each of them (along with its
+// arguments, its result or rows, and its error) to a JSON fixture file at path, to be served by the replay
+// connector. Both run through the interceptor, so the fixture holds the translated statements. The arguments
+// the redactor of the proxy deems secret (see Redactor) are stored as hashes, matched as such on replay.
+func NewRecordingConnector(inner sqlcommons.SQLConnector, path string) sqlcommons.SQLConnector {
+	return &recordingConn{inner: inner, path: path}
+}
+
+func (s *recordingConn) Open(logger logger.Logger, translator sqlcommons.SQLAdapter) (*sql.DB, error) {
+
+	// The inner DB maps the native errors, but leaves the logging to the outer one
+	innerDB, err := s.inner.Open(noLogger, translator)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder := &fixtureRecorder{path: s.path, fixture: fixture{Engine: s.Engine()}}
+	redactor := s.activeRedactor()
+	if err := recorder.save(); err != nil {
+		innerDB.Close()
+		return nil, err
+	}
+	return openProxy(s.Engine(), logger, translator, &innerConnector{
+		connect: func(ctx context.Context) (driver.Conn, error) {
+			conn, err := innerDB.Conn(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return &recordingDriverConn{conn: conn, recorder: recorder, redactor: redactor}, nil
+		},
+		close:       innerDB.Close,
+		keepSecrets: true,
+	}, s.interception), nil
+}
+
+func (s *recordingConn) GetNextSequenceQuery(sequenceName string) string {
+	return s.inner.GetNextSequenceQuery(sequenceName)
+}
+
+func (s *recordingConn) Engine() string {
+	return engineOf(s.inner)
+}
+
+type replayConn struct {
+	interception
+	path   string
+	engine string
+}
+
+// NewReplayConnector serves the fixture file written by the recording connector, without any DB. The statements
+// must be issued in the order they were recorded; any other fails with sqlerrors.UnexpectedStatement.
+func NewReplayConnector(path string) sqlcommons.SQLConnector {
+	return &replayConn{path: path}
+}
+
+func (s *replayConn) Open(logger logger.Logger, translator sqlcommons.SQLAdapter) (*sql.DB, error) {
+
+	f, err := loadFixture(s.path)
+	if err != nil {
+		return nil, err
+	}
+	s.engine = f.Engine
+
+	player := &fixturePlayer{fixture: f, redactor: s.activeRedactor()}
+	return openProxy(s.engine, logger, translator, &innerConnector{
+		connect: func(_ context.Context) (driver.Conn, error) {
+			return &replayDriverConn{player}, nil
+		},
+		keepSecrets: true,
+	}, s.interception), nil
+}
+
+func (s *replayConn) GetNextSequenceQuery(sequenceName string) string {
+	return sequenceQuery(s.engine, sequenceName)
+}
+
+func (s *replayConn) Engine() string {
+	return s.engine
+}
+
+var noLogger = logger.NewNoLogLogger()
+
+func engineOf(connector sqlcommons.SQLConnector) string {
+	if engine, ok := connector.(interface{ Engine() string }); ok {
+		return engine.Engine()
+	}
+	return ""
+}
+
+// innerConnector serves the connections of the connectors wrapping another one (or none), closing the
+// wrapped DB, if any, along with the proxy. Its connections get the SecretArg bind arguments as such if keepSecrets.
+type innerConnector struct {
+	connect     func(ctx context.Context) (driver.Conn, error)
+	close       func() error
+	keepSecrets bool
+}
+
+func (c *innerConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.connect(ctx)
+}
+
+func (c *innerConnector) Close() error {
+	if c.close == nil {
+		return nil
+	}
+	return c.close()
+}
+
+func (c *innerConnector) Driver() driver.Driver {
+	return fixtureDriver{c}
+}
+
+type fixtureDriver struct {
+	connector *innerConnector
+}
+
+func (d fixtureDriver) Open(_ string) (driver.Conn, error) {
+	return d.connector.Connect(context.Background())
+}
+
+// connStmt runs a "prepared" statement on its connection, statement by statement.
+type connStmt struct {
+	conn interface {
+		driver.ExecerContext
+		driver.QueryerContext
+	}
+	query string
+}
+
+func (s *connStmt) Close() error {
+	return nil
+}
+
+func (s *connStmt) NumInput() int {
+	return -1
+}
+
+func (s *connStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, driver.ErrSkip
+}
+
+func (s *connStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, driver.ErrSkip
+}
+
+func (s *connStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *connStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+// recordingDriverConn runs the (already translated) statements on a connection of the inner DB.
+type recordingDriverConn struct {
+	conn     *sql.Conn
+	tx       *sql.Tx
+	recorder *fixtureRecorder
+	redactor *Redactor
+}
+
+type sqlQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func (c *recordingDriverConn) target() sqlQuerier {
+	if c.tx != nil {
+		return c.tx
+	}
+	return c.conn
+}
+
+func (c *recordingDriverConn) Prepare(query string) (driver.Stmt, error) {
+	return &connStmt{c, query}, nil
+}
+
+func (c *recordingDriverConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *recordingDriverConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *recordingDriverConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+
+	tx, err := c.conn.BeginTx(ctx, &sql.TxOptions{Isolation: sql.IsolationLevel(opts.Isolation), ReadOnly: opts.ReadOnly})
+	if recordErr := c.recorder.record(interaction{Operation: OpBegin, Error: errorText(err)}); recordErr != nil {
+		return nil, recordErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.tx = tx
+	return &recordingTx{c}, nil
+}
+
+func (c *recordingDriverConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+
+	result, err := c.target().ExecContext(WithNativeSQL(ctx), query, sqlArgs(args)...)
+	it := interaction{Operation: OpExec, Query: query, Args: fixtureArgs(c.redactor, query, args), Error: errorText(err)}
+	var recorded fixtureResult
+	if err == nil {
+		if id, idErr := result.LastInsertId(); idErr == nil {
+			recorded.lastInsertID, it.LastInsertID = &id, &id
+		}
+		if rows, rowsErr := result.RowsAffected(); rowsErr == nil {
+			recorded.rowsAffected, it.RowsAffected = &rows, &rows
+		}
+	}
+	if recordErr := c.recorder.record(it); recordErr != nil {
+		return nil, recordErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return recorded, nil
+}
+
+// QueryContext reads the whole result, to record it.
+func (c *recordingDriverConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+
+	it := interaction{Operation: OpQuery, Query: query, Args: fixtureArgs(c.redactor, query, args)}
+	rows, err := c.target().QueryContext(WithNativeSQL(ctx), query, sqlArgs(args)...)
+	if err != nil {
+		it.Error = errorText(err)
+		if recordErr := c.recorder.record(it); recordErr != nil {
+			return nil, recordErr
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	read, err := readRows(rows)
+	if err != nil {
+		// Recorded as a failed query, as it fails here
+		it.Error = errorText(err)
+		if recordErr := c.recorder.record(it); recordErr != nil {
+			return nil, recordErr
+		}
+		return nil, err
+	}
+	for _, row := range read.rows {
+		it.Rows = append(it.Rows, fixtureValues(row))
+	}
+	it.Columns, it.Error = read.columns, errorText(read.err)
+	if recordErr := c.recorder.record(it); recordErr != nil {
+		return nil, recordErr
+	}
+	return read, nil
+}
+
+func (c *recordingDriverConn) Ping(ctx context.Context) error {
+	return c.conn.PingContext(ctx)
+}
+
+type recordingTx struct {
+	conn *recordingDriverConn
+}
+
+func (t *recordingTx) Commit() error {
+	return t.end(OpCommit, t.conn.tx.Commit)
+}
+
+func (t *recordingTx) Rollback() error {
+	return t.end(OpRollback, t.conn.tx.Rollback)
+}
+
+func (t *recordingTx) end(op Operation, end func() error) error {
+	err := end()
+	t.conn.tx = nil
+	if recordErr := t.conn.recorder.record(interaction{Operation: op, Error: errorText(err)}); recordErr != nil {
+		return recordErr
+	}
+	return err
+}
+
+// readRows reads the whole result into memory, keeping the error that ended it, if any.
+func readRows(rows *sql.Rows) (*fixtureRows, error) {
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	read := &fixtureRows{columns: columns}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(values))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := make([]driver.Value, len(values))
+		for i, value := range values {
+			row[i] = value
+		}
+		read.rows = append(read.rows, row)
+	}
+	read.err = rows.Err()
+	return read, nil
+}
+
+func sqlArgs(args []driver.NamedValue) []interface{} {
+	converted := make([]interface{}, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			converted[i] = sql.Named(arg.Name, arg.Value)
+		} else {
+			converted[i] = arg.Value
+		}
+	}
+	return converted
+}
+
+// replayDriverConn serves the interactions of the fixture.
+type replayDriverConn struct {
+	player *fixturePlayer
+}
+
+func (c *replayDriverConn) Prepare(query string) (driver.Stmt, error) {
+	return &connStmt{c, query}, nil
+}
+
+func (c *replayDriverConn) Close() error {
+	return nil
+}
+
+func (c *replayDriverConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *replayDriverConn) BeginTx(_ context.Context, _ driver.TxOptions) (driver.Tx, error) {
+	if err := c.replay(OpBegin); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *replayDriverConn) Commit() error {
+	return c.replay(OpCommit)
+}
+
+func (c *replayDriverConn) Rollback() error {
+	return c.replay(OpRollback)
+}
+
+func (c *replayDriverConn) replay(op Operation) error {
+	it, err := c.player.play(op, "", nil)
+	if err != nil {
+		return err
+	}
+	return textError(it.Error)
+}
+
+func (c *replayDriverConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	it, err := c.player.play(OpExec, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := textError(it.Error); err != nil {
+		return nil, err
+	}
+	return fixtureResult{it.LastInsertID, it.RowsAffected}, nil
+}
+
+func (c *replayDriverConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+
+	it, err := c.player.play(OpQuery, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if it.Columns == nil && it.Error != "" {
+		return nil, textError(it.Error)
+	}
+
+	rows := &fixtureRows{columns: it.Columns, err: textError(it.Error)}
+	for _, recorded := range it.Rows {
+		row := make([]driver.Value, len(recorded))
+		for i, value := range recorded {
+			row[i] = value.value
+		}
+		rows.rows = append(rows.rows, row)
+	}
+	return rows, nil
+}
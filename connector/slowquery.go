@@ -0,0 +1,158 @@
+package connector
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cdleo/go-commons/logger"
+)
+
+type explainCtxKey struct{}
+
+// isExplain tells whether the statement is the plan capture of a slow statement, which the hooks guarding the DB
+// (policy, admission, breaker) let through without accounting for it.
+func isExplain(ctx context.Context) bool {
+	return ctx.Value(explainCtxKey{}) != nil
+}
+
+// SlowQueryHook logs the statements whose duration reaches a threshold and, optionally,
+// the execution plan of the ones reaching a second (usually higher) threshold.
+type SlowQueryHook struct {
+	logger           logger.Logger
+	threshold        time.Duration
+	explainThreshold time.Duration
+	explainTimeout   time.Duration
+	db               *sql.DB
+	engine           string
+}
+
+func NewSlowQueryHook(logger logger.Logger, threshold time.Duration) *SlowQueryHook {
+	return &SlowQueryHook{
+		logger:         logger,
+		threshold:      threshold,
+		explainTimeout: 5 * time.Second,
+	}
+}
+
+// WithExplain enables the capture of the execution plan for the statements slower than the given threshold.
+func (h *SlowQueryHook) WithExplain(threshold time.Duration) *SlowQueryHook {
+	h.explainThreshold = threshold
+	return h
+}
+
+func (h *SlowQueryHook) bind(db *sql.DB, engine string) {
+	h.db = db
+	h.engine = engine
+}
+
+func (h *SlowQueryHook) Before(_ context.Context, _ *QueryEvent) error {
+	return nil
+}
+
+func (h *SlowQueryHook) After(ctx context.Context, event *QueryEvent) {
+
+	if event.Duration < h.threshold || isExplain(ctx) {
+		return
+	}
+
+	h.logger.Warnf("Slow %s: %s; args = %v; rows = %d; caller = %s; fingerprint = %s (%s)",
+		strings.ToLower(string(event.Operation)), event.RedactedQuery(), event.RedactedArgs(), event.RowsAffected, callerLocation(), event.Fingerprint, event.Duration)
+
+	if h.explainThreshold > 0 && event.Duration >= h.explainThreshold && h.db != nil && isExplainable(event.Query) {
+		go h.explain(event.Query, event.RedactedQuery(), namedValuesToArgs(event.Args))
+	}
+}
+
+func (h *SlowQueryHook) explain(query string, redactedQuery string, args []interface{}) {
+
+	// The statement is already translated
+	ctx, cancel := context.WithTimeout(WithNativeSQL(context.WithValue(context.Background(), explainCtxKey{}, true)), h.explainTimeout)
+	defer cancel()
+
+	plan, err := explainPlan(ctx, h.db, h.engine, query, args)
+	if err != nil {
+		h.logger.Warnf("Unable to explain slow statement: %s (%v)", redactedQuery, err)
+		return
+	}
+	h.logger.Warnf("Execution plan of slow statement: %s\n%s", redactedQuery, plan)
+}
+
+func explainPlan(ctx context.Context, db *sql.DB, engine string, query string, args []interface{}) (string, error) {
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	var planQuery string
+	switch engine {
+	case oracleEngine:
+		if _, err := conn.ExecContext(ctx, "EXPLAIN PLAN FOR "+query); err != nil {
+			return "", err
+		}
+		planQuery, args = "SELECT PLAN_TABLE_OUTPUT FROM TABLE(DBMS_XPLAN.DISPLAY())", nil
+	case postgresEngine:
+		planQuery = "EXPLAIN " + query
+	case sqlite3Engine:
+		planQuery = "EXPLAIN QUERY PLAN " + query
+	default:
+		return "", fmt.Errorf("execution plan not supported for engine [%s]", engine)
+	}
+
+	rows, err := conn.QueryContext(ctx, planQuery, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+
+	var lines []string
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+		lines = append(lines, values[len(values)-1].String)
+	}
+	return strings.Join(lines, "\n"), rows.Err()
+}
+
+func isExplainable(query string) bool {
+
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "MERGE", "WITH":
+		return true
+	default:
+		return false
+	}
+}
+
+func namedValuesToArgs(namedValues []driver.NamedValue) []interface{} {
+
+	args := make([]interface{}, len(namedValues))
+	for i, nv := range namedValues {
+		if nv.Name != "" {
+			args[i] = sql.Named(nv.Name, nv.Value)
+		} else {
+			args[i] = nv.Value
+		}
+	}
+	return args
+}
@@ -1,32 +1,61 @@
 package connector
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 
 	"github.com/cdleo/go-commons/logger"
 	"github.com/cdleo/go-commons/sqlcommons"
-	"github.com/mattn/go-sqlite3"
 )
 
 type sqlite3Conn struct {
+	interception
 	url string
 }
 
-const sqlite3ProxyName = "sqlite3-proxy"
+const sqlite3Engine = "SQLite3"
 
+// NewSqlite3Connector runs on go-sqlite3, or on the pure-Go driver when built without cgo or with the sqlite_purego
+// build tag (see sqlite3_purego.go).
 func NewSqlite3Connector(url string) sqlcommons.SQLConnector {
 	return &sqlite3Conn{
-		url,
+		url: url,
 	}
 }
 
 func (s *sqlite3Conn) Open(logger logger.Logger, translator sqlcommons.SQLAdapter) (*sql.DB, error) {
 
-	registerProxy(sqlite3ProxyName, logger, translator, &sqlite3.SQLiteDriver{})
+	sqlConnector, err := newSqlite3Connector(s.dsn())
+	if err != nil {
+		return nil, err
+	}
+
+	return openProxy(sqlite3Engine, logger, translator, sqlConnector, s.interception), nil
+}
 
-	return sql.Open(sqlite3ProxyName, s.url)
+// dsn sets the busy timeout (how long a statement waits for a locked DB) to the statement timeout, if any.
+func (s *sqlite3Conn) dsn() string {
+	timeout := s.timeouts.statementTimeout()
+	if timeout <= 0 || strings.Contains(s.url, "_timeout=") {
+		return s.url
+	}
+	separator := "?"
+	if strings.Contains(s.url, "?") {
+		separator = "&"
+	}
+	return fmt.Sprintf("%s%s_busy_timeout=%d", s.url, separator, timeout.Milliseconds())
 }
 
 func (s *sqlite3Conn) GetNextSequenceQuery(sequenceName string) string {
 	return sequenceName
 }
+
+func (s *sqlite3Conn) Engine() string {
+	return sqlite3Engine
+}
+
+func (s *sqlite3Conn) BulkInsert(ctx context.Context, db *sql.DB, translator sqlcommons.SQLAdapter, table string, columns []string, rows [][]interface{}) (int64, error) {
+	return multiRowInsert(sqlite3Engine, table, columns, sqlite3MaxVariables).insert(ctx, db, rows)
+}
@@ -0,0 +1,28 @@
+//go:build cgo && !sqlite_purego
+
+package connector
+
+import (
+	"database/sql/driver"
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+func newSqlite3Connector(dsn string) (driver.Connector, error) {
+	return newDSNConnector(&sqlite3.SQLiteDriver{}, dsn)
+}
+
+// sqliteErrorCode returns the primary code of a go-sqlite3 error.
+func sqliteErrorCode(err error) (int, bool) {
+	var sqliteError sqlite3.Error
+	if errors.As(err, &sqliteError) {
+		return int(sqliteError.Code), true
+	}
+	return 0, false
+}
+
+// sqliteError returns the go-sqlite3 error of the codes.
+func sqliteError(code int, extendedCode int, _ error) error {
+	return sqlite3.Error{Code: sqlite3.ErrNo(code), ExtendedCode: sqlite3.ErrNoExtended(extendedCode)}
+}
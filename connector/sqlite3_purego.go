@@ -0,0 +1,182 @@
+//go:build !cgo || sqlite_purego
+
+package connector
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"modernc.org/sqlite"
+)
+
+// Without cgo, or with the sqlite_purego build tag, go-sqlite3 is left out and NewSqlite3Connector runs on
+// modernc.org/sqlite, which doesn't need cgo: the build tag is the only selector of the driver. The go-sqlite3
+// params of the url (e.g. _foreign_keys or _busy_timeout) are turned into the pragmas of the driver.
+
+func newSqlite3Connector(dsn string) (driver.Connector, error) {
+	sqlConnector, err := newDSNConnector(&sqlite.Driver{}, pureGoDSN(dsn))
+	if err != nil {
+		return nil, err
+	}
+	return &pureGoConnector{sqlConnector}, nil
+}
+
+// sqliteErrorCode returns the primary code of a pure-Go SQLite3 error.
+func sqliteErrorCode(err error) (int, bool) {
+	var sqliteError *sqlite.Error
+	if errors.As(err, &sqliteError) {
+		return sqliteError.Code() & 0xff, true
+	}
+	return 0, false
+}
+
+// sqliteError returns the stand-in error, as the pure-Go errors can't be built outside of the driver: the faults
+// injected on these builds don't go through the code mapping of the adapter.
+func sqliteError(_ int, _ int, standIn error) error {
+	return standIn
+}
+
+// pureGoPragmas maps the go-sqlite3 params to the pragmas they set.
+var pureGoPragmas = map[string]string{
+	"_auto_vacuum":              "auto_vacuum",
+	"_vacuum":                   "auto_vacuum",
+	"_busy_timeout":             "busy_timeout",
+	"_timeout":                  "busy_timeout",
+	"_case_sensitive_like":      "case_sensitive_like",
+	"_cslike":                   "case_sensitive_like",
+	"_defer_foreign_keys":       "defer_foreign_keys",
+	"_defer_fk":                 "defer_foreign_keys",
+	"_foreign_keys":             "foreign_keys",
+	"_fk":                       "foreign_keys",
+	"_ignore_check_constraints": "ignore_check_constraints",
+	"_journal_mode":             "journal_mode",
+	"_journal":                  "journal_mode",
+	"_locking_mode":             "locking_mode",
+	"_locking":                  "locking_mode",
+	"_query_only":               "query_only",
+	"_recursive_triggers":       "recursive_triggers",
+	"_rt":                       "recursive_triggers",
+	"_secure_delete":            "secure_delete",
+	"_synchronous":              "synchronous",
+	"_sync":                     "synchronous",
+}
+
+// pureGoDSN turns the go-sqlite3 params of the dsn into _pragma ones, and stores the times in the go-sqlite3 format.
+// As on go-sqlite3, a statement waits up to 5 seconds for a locked DB by default.
+func pureGoDSN(dsn string) string {
+	path, rawQuery, _ := strings.Cut(dsn, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return dsn
+	}
+	if !query.Has("_busy_timeout") && !query.Has("_timeout") {
+		query.Set("_busy_timeout", "5000")
+	}
+	params := make([]string, 0, len(query))
+	for param := range query {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+	for _, param := range params {
+		if pragma, ok := pureGoPragmas[param]; ok {
+			for _, value := range query[param] {
+				query.Add("_pragma", fmt.Sprintf("%s(%s)", pragma, value))
+			}
+			query.Del(param)
+		}
+	}
+	if !query.Has("_time_format") {
+		query.Set("_time_format", "sqlite")
+	}
+	return path + "?" + query.Encode()
+}
+
+// pureGoConnector binds the :n placeholders by position on the pure-Go driver, which binds them by name only
+// (unlike go-sqlite3), by naming each positional arg after its ordinal.
+type pureGoConnector struct {
+	driver.Connector
+}
+
+type pureGoDriverConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+type pureGoDriverStmt interface {
+	driver.Stmt
+	driver.StmtExecContext
+	driver.StmtQueryContext
+}
+
+func (c *pureGoConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	driverConn, ok := conn.(pureGoDriverConn)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("unsupported pure-Go SQLite3 connection %T", conn)
+	}
+	return &pureGoConn{driverConn}, nil
+}
+
+type pureGoConn struct {
+	pureGoDriverConn
+}
+
+func (c *pureGoConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.pureGoDriverConn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	driverStmt, ok := stmt.(pureGoDriverStmt)
+	if !ok {
+		stmt.Close()
+		return nil, fmt.Errorf("unsupported pure-Go SQLite3 statement %T", stmt)
+	}
+	return &pureGoStmt{driverStmt}, nil
+}
+
+func (c *pureGoConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.pureGoDriverConn.ExecContext(ctx, query, nameOrdinals(args))
+}
+
+func (c *pureGoConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.pureGoDriverConn.QueryContext(ctx, query, nameOrdinals(args))
+}
+
+type pureGoStmt struct {
+	pureGoDriverStmt
+}
+
+func (s *pureGoStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.pureGoDriverStmt.ExecContext(ctx, nameOrdinals(args))
+}
+
+func (s *pureGoStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.pureGoDriverStmt.QueryContext(ctx, nameOrdinals(args))
+}
+
+func nameOrdinals(args []driver.NamedValue) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		if arg.Name == "" {
+			arg.Name = strconv.Itoa(arg.Ordinal)
+		}
+		named[i] = arg
+	}
+	return named
+}
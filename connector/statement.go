@@ -0,0 +1,146 @@
+package connector
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cdleo/go-sqldb/fingerprint"
+)
+
+type StatementKind string
+
+const (
+	StatementSelect StatementKind = "SELECT"
+	StatementDML    StatementKind = "DML"
+	StatementDDL    StatementKind = "DDL"
+	StatementPLSQL  StatementKind = "PLSQL"
+	StatementOther  StatementKind = "OTHER"
+)
+
+// ClassifyStatement returns the kind of a (translated) statement and its main command, e.g. (DML, "DELETE").
+// Comments and literals (Oracle q'[...]' and PostgreSQL $$...$$ ones too) are skipped, and so are the common table
+// expressions of a WITH clause, unless they modify data (e.g. a DELETE ... RETURNING on PostgreSQL). EXPLAIN is
+// classified as the statement it explains, since EXPLAIN ANALYZE runs it.
+func ClassifyStatement(query string) (StatementKind, string) {
+
+	words := topLevelWords(query)
+	for len(words) > 0 && words[0] == "EXPLAIN" {
+		words = words[1:]
+		for len(words) > 0 && !isStatementCommand(words[0]) {
+			words = words[1:]
+		}
+	}
+	if len(words) == 0 {
+		return StatementOther, ""
+	}
+
+	command := words[0]
+	if command == "BEGIN" && startsTransaction(words) {
+		return StatementOther, command
+	}
+	if command == "WITH" {
+		if command = modifyingCommand(query); command == "" {
+			for _, word := range words[1:] {
+				if word == "SELECT" || word == "VALUES" {
+					command = word
+					break
+				}
+			}
+		}
+	}
+
+	switch command {
+	case "SELECT", "VALUES", "SHOW", "DESCRIBE":
+		return StatementSelect, command
+	case "INSERT", "UPDATE", "DELETE", "MERGE", "UPSERT", "REPLACE", "COPY":
+		return StatementDML, command
+	case "CREATE", "ALTER", "DROP", "TRUNCATE", "RENAME", "COMMENT", "GRANT", "REVOKE", "REINDEX", "VACUUM":
+		return StatementDDL, command
+	case "BEGIN", "DECLARE", "CALL", "EXEC", "EXECUTE", "DO":
+		return StatementPLSQL, command
+	default:
+		return StatementOther, command
+	}
+}
+
+// startsTransaction tells a BEGIN starting a transaction (BEGIN; BEGIN TRANSACTION, BEGIN IMMEDIATE on SQLite3,
+// BEGIN ISOLATION LEVEL on PostgreSQL) apart from the BEGIN of a PL/SQL block.
+func startsTransaction(words []string) bool {
+	if len(words) == 1 {
+		return true
+	}
+	switch words[1] {
+	case "TRANSACTION", "WORK", "DEFERRED", "IMMEDIATE", "EXCLUSIVE", "ISOLATION", "READ", "NOT", "DEFERRABLE":
+		return true
+	default:
+		return false
+	}
+}
+
+func isStatementCommand(word string) bool {
+	switch word {
+	case "SELECT", "VALUES", "WITH", "INSERT", "UPDATE", "DELETE", "MERGE", "UPSERT", "REPLACE":
+		return true
+	default:
+		return false
+	}
+}
+
+// modifyingCommand returns the first data-modifying command found at any depth of the statement, skipping the
+// locking clauses (FOR UPDATE, FOR NO KEY UPDATE).
+func modifyingCommand(query string) string {
+	words := statementWords(query, true)
+	for i, word := range words {
+		switch word {
+		case "INSERT", "DELETE", "MERGE":
+			return word
+		case "UPDATE":
+			if i == 0 || (words[i-1] != "FOR" && words[i-1] != "KEY") {
+				return word
+			}
+		}
+	}
+	return ""
+}
+
+// Placeholder returns the n-th bind placeholder in the engine's native syntax.
+func Placeholder(engine string, n int) string {
+	switch engine {
+	case postgresEngine:
+		return fmt.Sprintf("$%d", n)
+	case sqlite3Engine:
+		return "?"
+	default:
+		return fmt.Sprintf(":%d", n)
+	}
+}
+
+// sequenceQuery returns the query the connector of the engine issues to get the next value of a sequence.
+func sequenceQuery(engine string, sequenceName string) string {
+	switch engine {
+	case oracleEngine:
+		return (&oracleConn{}).GetNextSequenceQuery(sequenceName)
+	case postgresEngine:
+		return (&pgSqlConn{}).GetNextSequenceQuery(sequenceName)
+	default:
+		return sequenceName
+	}
+}
+
+// topLevelWords returns the upper-cased words of the statement outside literals, comments and parentheses.
+func topLevelWords(query string) []string {
+	return statementWords(query, false)
+}
+
+// statementWords returns the upper-cased words of the statement outside literals and comments, and outside
+// parentheses too unless nested.
+func statementWords(query string, nested bool) []string {
+
+	var words []string
+	for _, word := range fingerprint.Words(query) {
+		if word.Depth == 0 || nested {
+			words = append(words, strings.ToUpper(word.Text))
+		}
+	}
+	return words
+}
@@ -0,0 +1,27 @@
+package connector
+
+import (
+	"context"
+)
+
+type tagsCtxKey struct{}
+
+// WithTag returns a copy of ctx carrying a tag (e.g. the route or the caller's identity) for the hooks
+// of the statements executed with it.
+func WithTag(ctx context.Context, key string, value string) context.Context {
+
+	tags := map[string]string{key: value}
+	for k, v := range TagsFromContext(ctx) {
+		if k != key {
+			tags[k] = v
+		}
+	}
+	return context.WithValue(ctx, tagsCtxKey{}, tags)
+}
+
+func TagsFromContext(ctx context.Context) map[string]string {
+	if tags, ok := ctx.Value(tagsCtxKey{}).(map[string]string); ok {
+		return tags
+	}
+	return nil
+}
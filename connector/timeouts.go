@@ -0,0 +1,175 @@
+package connector
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/cdleo/go-sqldb/sqlerrors"
+)
+
+// Timeouts are applied to the statements whose context has no deadline of its own. Default applies
+// to any operation without a specific timeout; zero means no timeout.
+type Timeouts struct {
+	Default time.Duration
+	Query   time.Duration
+	Exec    time.Duration
+	Begin   time.Duration
+	Commit  time.Duration
+}
+
+func (t Timeouts) For(op Operation) time.Duration {
+	var timeout time.Duration
+	switch op {
+	case OpQuery:
+		timeout = t.Query
+	case OpExec:
+		timeout = t.Exec
+	case OpBegin:
+		timeout = t.Begin
+	case OpCommit:
+		timeout = t.Commit
+	}
+	if timeout == 0 {
+		return t.Default
+	}
+	return timeout
+}
+
+// statementTimeout is the timeout pushed to the server, which doesn't tell queries from executions.
+func (t Timeouts) statementTimeout() time.Duration {
+	if t.For(OpQuery) > t.For(OpExec) {
+		return t.For(OpQuery)
+	}
+	return t.For(OpExec)
+}
+
+func (t Timeouts) withTimeout(ctx context.Context, op Operation) (context.Context, context.CancelFunc) {
+	if timeout := t.For(op); timeout > 0 {
+		if _, ok := ctx.Deadline(); !ok {
+			return context.WithTimeout(ctx, timeout)
+		}
+	}
+	return ctx, func() {}
+}
+
+// query runs the query under the timeout, which is kept until the rows are closed.
+func (t Timeouts) query(ctx context.Context, query func(ctx context.Context) (driver.Rows, error)) (driver.Rows, error) {
+
+	timeoutCtx, cancel := t.withTimeout(ctx, OpQuery)
+	rows, err := query(timeoutCtx)
+	if err != nil {
+		cancel()
+		return nil, timeoutError(timeoutCtx, err)
+	}
+	if timeoutCtx == ctx {
+		return rows, nil
+	}
+	return &timeoutRows{rows, timeoutCtx, cancel}, nil
+}
+
+// timeoutError tells the failures caused by an expired deadline apart, as sqlerrors.QueryTimeout.
+func timeoutError(ctx context.Context, err error) error {
+	if err == nil || errors.Is(err, sqlerrors.QueryTimeout) || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", sqlerrors.QueryTimeout, err)
+}
+
+// timeoutRows keeps the deadline of the query until the rows are closed.
+type timeoutRows struct {
+	driver.Rows
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (r *timeoutRows) Next(dest []driver.Value) error {
+	if err := r.Rows.Next(dest); err != io.EOF {
+		return timeoutError(r.ctx, err)
+	}
+	return io.EOF
+}
+
+func (r *timeoutRows) Close() error {
+	defer r.cancel()
+	return r.Rows.Close()
+}
+
+func (r *timeoutRows) HasNextResultSet() bool {
+	if rs, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return rs.HasNextResultSet()
+	}
+	return false
+}
+
+func (r *timeoutRows) NextResultSet() error {
+	if rs, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return timeoutError(r.ctx, rs.NextResultSet())
+	}
+	return io.EOF
+}
+
+func (r *timeoutRows) ColumnTypeScanType(index int) reflect.Type {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return ct.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(interface{})).Elem()
+}
+
+func (r *timeoutRows) ColumnTypeDatabaseTypeName(index int) string {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return ct.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *timeoutRows) ColumnTypeLength(index int) (int64, bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return ct.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+func (r *timeoutRows) ColumnTypeNullable(index int) (bool, bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeNullable); ok {
+		return ct.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+func (r *timeoutRows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return ct.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
+
+// timeoutTx bounds the commit, which the drivers can't cancel: once expired, the caller gets the timeout
+// (the outcome of the transaction being unknown) and the connection is discarded as soon as the driver returns.
+type timeoutTx struct {
+	driver.Tx
+	conn    *wrappedConn
+	timeout time.Duration
+}
+
+func (t *timeoutTx) Commit() error {
+
+	done := make(chan error, 1)
+	go func() {
+		done <- t.Tx.Commit()
+	}()
+
+	timer := time.NewTimer(t.timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		t.conn.pending = done
+		return fmt.Errorf("%w: commit exceeded %s (%w)", sqlerrors.QueryTimeout, t.timeout, driver.ErrBadConn)
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Package fingerprint provides a stable identifier per query shape, for metrics, slow query grouping and caching.
+It understands the Oracle, PostgreSQL and SQLite3 lexical syntax.
+*/
+package fingerprint
+
+import (
+	"encoding/hex"
+	"hash/fnv"
+	"strings"
+)
+
+const literalMark = "?"
+
+// Normalize returns the shape of a query: comments (but optimizer hints) are stripped, literals and
+// bind placeholders are replaced by "?", IN-lists and multi-row VALUES are collapsed, whitespace is
+// collapsed and unquoted words are lowercased.
+func Normalize(query string) string {
+
+	tokens := collapseLists(tokenize(query))
+
+	var sb strings.Builder
+	for i, tok := range tokens {
+		if i > 0 && needsSpace(tokens[i-1], tok) {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(tok.text)
+	}
+	return sb.String()
+}
+
+// Fingerprint returns a short hash of the normalized query.
+func Fingerprint(query string) string {
+	hash := fnv.New64a()
+	hash.Write([]byte(Normalize(query)))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Word is a word of a query, outside its literals, quoted identifiers and comments.
+type Word struct {
+	Text  string // Lowercased
+	Depth int    // Parentheses enclosing the word
+}
+
+// Words returns the words of a query, in order, e.g. to tell its commands apart from its literals.
+func Words(query string) []Word {
+
+	var words []Word
+	depth := 0
+	for _, tok := range tokenize(query) {
+		switch {
+		case tok.kind == word:
+			words = append(words, Word{tok.text, depth})
+		case tok.kind == punct && tok.text == "(":
+			depth++
+		case tok.kind == punct && tok.text == ")":
+			depth--
+		}
+	}
+	return words
+}
+
+func needsSpace(prev token, next token) bool {
+	switch {
+	case next.kind == punct && (next.text == "," || next.text == ")" || next.text == ";" || next.text == "." || next.text == "::"):
+		return false
+	case prev.kind == punct && (prev.text == "(" || prev.text == "." || prev.text == "::"):
+		return false
+	default:
+		return true
+	}
+}
+
+// collapseLists replaces "in (?, ?, ...)" by "in (?+)" and drops the repeated "(?, ?)" rows of a VALUES clause.
+func collapseLists(tokens []token) []token {
+
+	var collapsed []token
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if tok.kind == punct && tok.text == "(" {
+			if end, ok := markList(tokens, i); ok {
+				switch previousWord(collapsed) {
+				case "in":
+					collapsed = append(collapsed, tok, token{literal, literalMark + "+"}, tokens[end])
+					i = end
+					continue
+				case ",":
+					if isRowEnd(collapsed) {
+						collapsed = collapsed[:len(collapsed)-1]
+						i = end
+						continue
+					}
+				}
+			}
+		}
+		collapsed = append(collapsed, tok)
+	}
+	return collapsed
+}
+
+// markList tells whether the parenthesis at start opens a list made only of "?" marks, returning its end.
+func markList(tokens []token, start int) (int, bool) {
+	expectMark := true
+	for i := start + 1; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch {
+		case expectMark && tok.kind == literal:
+			expectMark = false
+		case !expectMark && tok.kind == punct && tok.text == ",":
+			expectMark = true
+		case !expectMark && tok.kind == punct && tok.text == ")":
+			return i, true
+		default:
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+func previousWord(tokens []token) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+	return tokens[len(tokens)-1].text
+}
+
+// isRowEnd tells whether the tokens end with ") ," closing a row of marks of a VALUES clause.
+func isRowEnd(tokens []token) bool {
+	n := len(tokens)
+	if n < 2 || tokens[n-2].kind != punct || tokens[n-2].text != ")" {
+		return false
+	}
+	for i := n - 3; i >= 0; i-- {
+		if tokens[i].kind == punct && tokens[i].text == "(" {
+			return i > 0 && tokens[i-1].text == "values"
+		}
+		if tokens[i].kind != literal && tokens[i].text != "," {
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package fingerprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Normalize_StripsLiteralsAndWhitespace(t *testing.T) {
+	require.Equal(t,
+		"select id, name from customers where name = ? and age > ? and ratio < ?",
+		Normalize("SELECT id,   name\n\tFROM Customers WHERE name = 'O''Brien' AND age > 30 AND ratio < 1.5e-3"))
+}
+
+func Test_Normalize_Placeholders(t *testing.T) {
+	expected := "update customers set age = ? where name = ?"
+	require.Equal(t, expected, Normalize("UPDATE customers SET age = :1 WHERE name = :2"))
+	require.Equal(t, expected, Normalize("UPDATE customers SET age = $1 WHERE name = $2"))
+	require.Equal(t, expected, Normalize("UPDATE customers SET age = ? WHERE name = @name"))
+	require.Equal(t, expected, Normalize("UPDATE customers SET age = :age WHERE name = :name"))
+}
+
+func Test_Normalize_CollapsesLists(t *testing.T) {
+	require.Equal(t, Normalize("SELECT * FROM t WHERE id IN (1, 2, 3)"), Normalize("select * from t where id in (:1)"))
+	require.Equal(t, "select * from t where id in (?+)", Normalize("select * from t where id in (4,5)"))
+	require.Equal(t, "insert into t (a, b) values (?, ?)", Normalize("INSERT INTO t (a, b) VALUES (1, 'x'), (2, 'y'), (3, 'z')"))
+}
+
+func Test_Normalize_EngineSpecificQuoting(t *testing.T) {
+	require.Equal(t, `select "Mixed Case" from dual where a = ?`, Normalize(`SELECT "Mixed Case" FROM DUAL WHERE a = q'[it's]'`))
+	require.Equal(t, "select * from t where a = ? and b = ?", Normalize("select * from t where a = nQ'{x}' and b = E'it\\'s'"))
+	require.Equal(t, "select ?, ?", Normalize("SELECT $$it's$$, $fn$ body $$ nested $fn$"))
+	require.Equal(t, "select a::text from t", Normalize("SELECT a::TEXT FROM t"))
+}
+
+func Test_Normalize_Comments(t *testing.T) {
+	require.Equal(t, "select /*+ index(t idx) */ * from t", Normalize("SELECT /*+  INDEX(t idx) */ * FROM t -- trailing\n/*app='x'*/"))
+}
+
+func Test_Fingerprint_IsStable(t *testing.T) {
+	require.Equal(t, Fingerprint("SELECT * FROM t WHERE id = 1"), Fingerprint("select *\nfrom T where ID = 42"))
+	require.NotEqual(t, Fingerprint("SELECT * FROM t WHERE id = 1"), Fingerprint("SELECT * FROM u WHERE id = 1"))
+	require.Len(t, Fingerprint("SELECT 1"), 16)
+}
+
+func Test_Words_SkipLiteralsAndComments(t *testing.T) {
+	require.Equal(t, []Word{{"select", 0}, {"v", 0}, {"from", 0}, {"t", 0}, {"where", 0}, {"id", 0}, {"in", 0}, {"select", 1}, {"id", 1}, {"from", 1}, {"u", 1}},
+		Words(`SELECT q'[it's]' v, $$it's$$, "Quoted" FROM t /* DELETE */ WHERE id IN (SELECT id FROM u) -- UPDATE`))
+}
@@ -0,0 +1,206 @@
+package fingerprint
+
+import (
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	word tokenKind = iota
+	quoted
+	literal
+	punct
+	hint
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+var operators = []string{"->>", "<>", "!=", "<=", ">=", "||", "::", ":=", "=>", "->"}
+
+var closingQuote = map[byte]byte{'[': ']', '{': '}', '(': ')', '<': '>'}
+
+func tokenize(query string) []token {
+
+	var tokens []token
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case isSpace(c):
+			i++
+
+		case strings.HasPrefix(query[i:], "--"):
+			i = skipUntil(query, i+2, "\n")
+
+		case strings.HasPrefix(query[i:], "/*+"):
+			end := skipUntil(query, i+3, "*/")
+			tokens = append(tokens, token{hint, "/*+ " + strings.ToLower(strings.Join(strings.Fields(strings.TrimSuffix(query[i+3:end], "*/")), " ")) + " */"})
+			i = end
+
+		case strings.HasPrefix(query[i:], "/*"):
+			i = skipUntil(query, i+2, "*/")
+
+		case c == '\'':
+			i = skipQuoted(query, i+1, '\'', false)
+			tokens = append(tokens, token{literal, literalMark})
+
+		case isOracleQuote(query, i):
+			start := i + strings.IndexByte(query[i:], '\'') + 1
+			i = skipOracleQuote(query, start)
+			tokens = append(tokens, token{literal, literalMark})
+
+		case (c == 'E' || c == 'e') && i+1 < len(query) && query[i+1] == '\'':
+			i = skipQuoted(query, i+2, '\'', true)
+			tokens = append(tokens, token{literal, literalMark})
+
+		case strings.IndexByte("NnXxBb", c) >= 0 && i+1 < len(query) && query[i+1] == '\'':
+			i = skipQuoted(query, i+2, '\'', false)
+			tokens = append(tokens, token{literal, literalMark})
+
+		case c == '"' || c == '`':
+			end := skipQuoted(query, i+1, c, false)
+			tokens = append(tokens, token{quoted, query[i:end]})
+			i = end
+
+		case c == '$':
+			if end, ok := skipDollarQuote(query, i); ok {
+				tokens = append(tokens, token{literal, literalMark})
+				i = end
+			} else if end := skipWord(query, i+1); end > i+1 {
+				tokens = append(tokens, token{literal, literalMark})
+				i = end
+			} else {
+				tokens = append(tokens, token{punct, "$"})
+				i++
+			}
+
+		case (c == ':' || c == '@') && i+1 < len(query) && isWordChar(query[i+1]):
+			tokens = append(tokens, token{literal, literalMark})
+			i = skipWord(query, i+1)
+
+		case c == '?':
+			tokens = append(tokens, token{literal, literalMark})
+			i++
+
+		case isDigit(c) || (c == '.' && i+1 < len(query) && isDigit(query[i+1])):
+			tokens = append(tokens, token{literal, literalMark})
+			i = skipNumber(query, i)
+
+		case isWordChar(c):
+			end := skipWord(query, i)
+			tokens = append(tokens, token{word, strings.ToLower(query[i:end])})
+			i = end
+
+		default:
+			op := string(c)
+			for _, candidate := range operators {
+				if strings.HasPrefix(query[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			tokens = append(tokens, token{punct, op})
+			i += len(op)
+		}
+	}
+	return tokens
+}
+
+func skipUntil(query string, from int, terminator string) int {
+	if idx := strings.Index(query[from:], terminator); idx >= 0 {
+		return from + idx + len(terminator)
+	}
+	return len(query)
+}
+
+// skipQuoted returns the position after the closing quote, honouring doubled quotes (and backslashes, if escaped).
+func skipQuoted(query string, from int, quote byte, escaped bool) int {
+	for i := from; i < len(query); i++ {
+		switch {
+		case escaped && query[i] == '\\':
+			i++
+		case query[i] == quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i++
+			} else {
+				return i + 1
+			}
+		}
+	}
+	return len(query)
+}
+
+// isOracleQuote tells whether an Oracle alternative quoting literal (q'[...]', nq'{...}') starts at i.
+func isOracleQuote(query string, i int) bool {
+	if i > 0 && isWordChar(query[i-1]) {
+		return false
+	}
+	rest := strings.ToLower(query[i:min(i+4, len(query))])
+	return strings.HasPrefix(rest, "q'") && len(rest) > 2 || strings.HasPrefix(rest, "nq'") && len(rest) > 3
+}
+
+func skipOracleQuote(query string, from int) int {
+	delimiter := query[from]
+	if closing, ok := closingQuote[delimiter]; ok {
+		delimiter = closing
+	}
+	return skipUntil(query, from+1, string(delimiter)+"'")
+}
+
+// skipDollarQuote returns the position after a PostgreSQL dollar-quoted string ($$...$$ or $tag$...$tag$) starting at i.
+func skipDollarQuote(query string, i int) (int, bool) {
+	end := i + 1
+	if end < len(query) && !isDigit(query[end]) {
+		for end < len(query) && query[end] != '$' && isWordChar(query[end]) {
+			end++
+		}
+	}
+	if end >= len(query) || query[end] != '$' {
+		return 0, false
+	}
+	tag := query[i : end+1]
+	return skipUntil(query, end+1, tag), true
+}
+
+func skipWord(query string, from int) int {
+	i := from
+	for i < len(query) && isWordChar(query[i]) {
+		i++
+	}
+	return i
+}
+
+func skipNumber(query string, from int) int {
+	i := from
+	for i < len(query) && (isDigit(query[i]) || query[i] == '.') {
+		i++
+	}
+	if i < len(query) && (query[i] == 'e' || query[i] == 'E') {
+		j := i + 1
+		if j < len(query) && (query[j] == '+' || query[j] == '-') {
+			j++
+		}
+		if j < len(query) && isDigit(query[j]) {
+			i = j
+			for i < len(query) && isDigit(query[i]) {
+				i++
+			}
+		}
+	}
+	return i
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '$' || c == '#' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
@@ -0,0 +1,31 @@
+package sqldb
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cdleo/go-commons/sqlcommons"
+	"github.com/cdleo/go-sqldb/connector"
+)
+
+// BulkInsert inserts the rows (the values of the columns, in order) into the table all or none, with the fastest
+// path of the engine: COPY on PostgreSQL, array binding on Oracle and multi-row VALUES on SQLite3. A failure caused
+// by a given row is reported as a *sqlerrors.BulkInsertError. Note that the COPY bypasses the hooks.
+func BulkInsert(ctx context.Context, proxy *SQLProxy, table string, columns []string, rows [][]interface{}) (int64, error) {
+
+	if err := proxy.IsOpen(); err != nil {
+		return 0, sqlcommons.ConnectionClosed
+	}
+
+	inserter, ok := proxy.connector.(connector.BulkInserter)
+	if !ok {
+		return 0, sqlcommons.OpNotSupported
+	}
+	if len(columns) == 0 {
+		return 0, errors.New("no columns to insert")
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	return inserter.BulkInsert(ctx, proxy.db, proxy.translator, table, columns, rows)
+}
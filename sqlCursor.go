@@ -0,0 +1,91 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Cursor streams the rows of a query, scanned into T as Get does, without loading them into memory.
+// It must be closed once done.
+type Cursor[T any] struct {
+	ctx  context.Context
+	rows *sql.Rows
+	scan func(item *T) error
+	item T
+	err  error
+}
+
+func NewCursor[T any](ctx context.Context, q Querier, query string, args ...interface{}) (*Cursor[T], error) {
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	scan, err := newRowScanner[T](ctx, rows)
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	return &Cursor[T]{ctx: ctx, rows: rows, scan: scan}, nil
+}
+
+// Next advances to the next row, returning false once the rows are exhausted, the context is done or the scan fails.
+func (c *Cursor[T]) Next() bool {
+
+	if c.err != nil {
+		return false
+	}
+	if err := c.ctx.Err(); err != nil {
+		c.err = err
+		return false
+	}
+	if !c.rows.Next() {
+		c.err = c.rows.Err()
+		return false
+	}
+
+	var item T
+	if err := c.scan(&item); err != nil {
+		c.err = err
+		return false
+	}
+	c.item = item
+	return true
+}
+
+func (c *Cursor[T]) Value() T {
+	return c.item
+}
+
+func (c *Cursor[T]) Err() error {
+	return c.err
+}
+
+func (c *Cursor[T]) Close() error {
+	return c.rows.Close()
+}
+
+// Iter returns the rows of a query as an iterator, with the shape of an iter.Seq2[T, error]: the rows are
+// streamed, scanned into T as Get does, and closed once the loop ends or breaks. An error ends the iteration.
+func Iter[T any](ctx context.Context, q Querier, query string, args ...interface{}) func(yield func(T, error) bool) {
+
+	return func(yield func(T, error) bool) {
+		var zero T
+		cursor, err := NewCursor[T](ctx, q, query, args...)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer cursor.Close()
+
+		for cursor.Next() {
+			if !yield(cursor.Value(), nil) {
+				return
+			}
+		}
+		if err := cursor.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
@@ -1,8 +1,14 @@
 package sqldb
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -10,6 +16,8 @@ import (
 	"github.com/cdleo/go-commons/sqlcommons"
 	"github.com/cdleo/go-sqldb/adapter"
 	"github.com/cdleo/go-sqldb/connector"
+	"github.com/cdleo/go-sqldb/sqlerrors"
+	"github.com/jackc/pgconn"
 
 	"github.com/stretchr/testify/require"
 )
@@ -23,6 +31,33 @@ type Customers struct {
 	Dummy      string        `db:"not_existing_field"`
 }
 
+type recorderLogger struct {
+	logger.Logger
+	mu    sync.Mutex
+	lines []string
+}
+
+func newRecorderLogger() *recorderLogger {
+	return &recorderLogger{Logger: logger.NewNoLogLogger()}
+}
+
+func (l *recorderLogger) Warnf(msg string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(msg, v...))
+}
+
+func (l *recorderLogger) contains(text string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, line := range l.lines {
+		if strings.Contains(line, text) {
+			return true
+		}
+	}
+	return false
+}
+
 func Test_sqlConn_InitErr(t *testing.T) {
 	// Setup
 	sqlProxy := NewSQLProxyBuilder(connector.NewMockSQLConnector(false)).
@@ -322,6 +357,1134 @@ func Test_sqlConn_CanThrowValueLargerThanPrecisionError(t *testing.T) {
 	require.ErrorIs(t, err2, sqlcommons.ValueLargerThanPrecision)
 }
 
+func Test_sqlConn_SlowQueryLog(t *testing.T) {
+	// Setup
+	recorder := newRecorderLogger()
+	sqlProxy := NewSQLProxyBuilder(connector.NewSqlite3Connector(t.TempDir() + "/slow.db")).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		WithHooks(connector.NewSlowQueryHook(recorder, 0).WithExplain(time.Nanosecond)).
+		Build()
+
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+
+	require.NoError(t, createTablesHelper(sqlDB))
+	require.NoError(t, insertDataHelper(sqlDB))
+
+	// Exec
+	_, err = sqlDB.Exec("UPDATE customers SET age = :1 WHERE name = :2", 30, "Juan")
+	require.NoError(t, err)
+
+	require.True(t, recorder.contains("Slow exec: UPDATE customers SET age = :1 WHERE name = :2; args = [*** ***]; rows = 1; caller = "))
+	require.True(t, recorder.contains("sqlDB_test.go:"))
+	require.Eventually(t, func() bool {
+		return recorder.contains("Execution plan of slow statement: UPDATE customers")
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = Select[Customers](context.Background(), sqlDB, "SELECT name FROM customers WHERE name = :1", "Juan")
+	require.NoError(t, err)
+	require.False(t, recorder.contains("sqlCursor.go:"))
+}
+
+func Test_sqlConn_SlowQueryExplainBypassesHooks(t *testing.T) {
+	// Setup
+	recorder := newRecorderLogger()
+	admission := connector.NewAdmissionHook(10, 0)
+	sqlProxy := NewSQLProxyBuilder(connector.NewSqlite3Connector(t.TempDir()+"/explain.db")).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		WithPolicy(connector.NewPolicyHook().DenyPattern("^EXPLAIN")).
+		WithHooks(admission, connector.NewSlowQueryHook(recorder, 0).WithExplain(time.Nanosecond)).
+		Build()
+
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+
+	_, err = sqlDB.Exec("CREATE TABLE users (id INT, name TEXT)")
+	require.NoError(t, err)
+
+	// Exec
+	_, err = sqlDB.Exec("SELECT name FROM users WHERE id = :1", 1)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return recorder.contains("Execution plan of slow statement: SELECT name FROM users")
+	}, time.Second, 10*time.Millisecond)
+	require.False(t, recorder.contains("Unable to explain"))
+	require.Equal(t, uint64(2), admission.Stats().Admitted)
+}
+
+func Test_sqlConn_RedactsSensitiveArgs(t *testing.T) {
+	// Setup
+	recorder := newRecorderLogger()
+	sqlProxy := NewSQLProxyBuilder(connector.NewSqlite3Connector(":memory:")).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		WithRedactor(connector.NewRedactor().WithArgValues().WithNamePattern("(?i)^name$")).
+		WithHooks(connector.NewSlowQueryHook(recorder, 0)).
+		Build()
+
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+
+	_, err = sqlDB.Exec("CREATE TABLE users (name TEXT, age INT, password TEXT, token TEXT)")
+	require.NoError(t, err)
+
+	// Exec
+	_, err = sqlDB.Exec("INSERT INTO users (name, age, password, token) VALUES (:1, :2, :3, :4)", "Juan", 30, "s3cr3t", Secret("abc"))
+	require.NoError(t, err)
+	_, err = sqlDB.Exec("UPDATE users SET token = 'xyz' WHERE age = :1", Secret(30))
+	require.NoError(t, err)
+
+	require.True(t, recorder.contains("args = [*** 30 *** ***]"))
+	require.True(t, recorder.contains("UPDATE users SET token = '***' WHERE age = :1; args = [***]"))
+	require.False(t, recorder.contains("s3cr3t"))
+
+	var token string
+	require.NoError(t, sqlDB.QueryRow("SELECT token FROM users WHERE age = :1", Secret(30)).Scan(&token))
+	require.Equal(t, "xyz", token)
+}
+
+func Test_sqlConn_LogsArgsOfGivenColumns(t *testing.T) {
+	// Setup
+	recorder := newRecorderLogger()
+	sqlProxy := NewSQLProxyBuilder(connector.NewSqlite3Connector(":memory:")).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		WithRedactor(connector.NewRedactor().WithLoggedColumns("(?i)^(age|password)$")).
+		WithHooks(connector.NewSlowQueryHook(recorder, 0)).
+		Build()
+
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+
+	_, err = sqlDB.Exec("CREATE TABLE users (email TEXT, age INT, password TEXT)")
+	require.NoError(t, err)
+
+	// Exec
+	_, err = sqlDB.Exec("INSERT INTO users (email, age, password) VALUES (:1, :2, :3)", "juan@example.com", 30, "s3cr3t")
+	require.NoError(t, err)
+
+	require.True(t, recorder.contains("args = [*** 30 ***]"))
+	require.False(t, recorder.contains("juan@example.com"))
+}
+
+func Test_sqlConn_CommentsStatements(t *testing.T) {
+	// Setup
+	type traceKey struct{}
+	recorder := newRecorderLogger()
+	sqlProxy := NewSQLProxyBuilder(connector.NewSqlite3Connector(":memory:")).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		WithHooks(
+			connector.NewCommenterHook().WithStaticTag("app", "billing's */").WithContextValue("traceparent", traceKey{}),
+			connector.NewSlowQueryHook(recorder, 0),
+		).
+		Build()
+
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+
+	require.NoError(t, createTablesHelper(sqlDB))
+
+	ctx := connector.WithTag(context.Background(), "route", "/customers/{id}")
+	ctx = context.WithValue(ctx, traceKey{}, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	// Exec
+	rows, err := sqlDB.QueryContext(ctx, "SELECT /*+ INDEX(c) */ name FROM customers c;")
+	require.NoError(t, err)
+	rows.Close()
+
+	require.True(t, recorder.contains("SELECT /*+ INDEX(c) */ name FROM customers c /*app='billing%27s%20%2A%2F',route='%2Fcustomers%2F%7Bid%7D',traceparent='00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01'*/;"))
+}
+
+func Test_sqlConn_AuditsDataModifyingStatements(t *testing.T) {
+	// Setup
+	records := make(chan connector.AuditRecord, 10)
+	audit := connector.NewAuditHook(connector.NewChannelAuditSink(records), logger.NewNoLogLogger())
+	sqlProxy := NewSQLProxyBuilder(connector.NewSqlite3Connector(":memory:")).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		WithHooks(audit).
+		Build()
+
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+
+	require.NoError(t, createTablesHelper(sqlDB))
+	require.NoError(t, insertDataHelper(sqlDB))
+
+	// Exec
+	ctx := connector.WithTag(context.Background(), connector.UserTag, "jdoe")
+	_, err = sqlDB.ExecContext(ctx, "UPDATE customers SET age = :1 WHERE cust_group = :2", 40, 1)
+	require.NoError(t, err)
+	_, err = sqlDB.QueryContext(ctx, "SELECT * FROM customers")
+	require.NoError(t, err)
+	audit.Close()
+	close(records)
+
+	var audited []connector.AuditRecord
+	for record := range records {
+		audited = append(audited, record)
+	}
+	require.Len(t, audited, 7)
+	require.Equal(t, connector.StatementDDL, audited[0].Kind)
+	require.Equal(t, "CREATE", audited[0].Command)
+
+	last := audited[6]
+	require.Equal(t, "jdoe", last.Identity)
+	require.Equal(t, connector.StatementDML, last.Kind)
+	require.Equal(t, "UPDATE", last.Command)
+	require.Equal(t, []string{"***", "***"}, last.Args)
+	require.Equal(t, int64(3), last.RowsAffected)
+	require.Equal(t, "success", last.Outcome)
+}
+
+func Test_sqlConn_AuditsIntoTable(t *testing.T) {
+	// Setup
+	audit := connector.NewAuditHook(connector.NewTableAuditSink("audit_log"), logger.NewNoLogLogger())
+	sqlProxy := NewSQLProxyBuilder(connector.NewSqlite3Connector(t.TempDir() + "/audit.db")).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		WithHooks(audit).
+		Build()
+
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+
+	_, err = sqlDB.Exec(`CREATE TABLE audit_log (event_time TIMESTAMP, identity TEXT, kind TEXT, command TEXT, statement TEXT,
+		args TEXT, rows_affected INT, duration_ms INT, outcome TEXT, error TEXT)`)
+	require.NoError(t, err)
+	require.NoError(t, createTablesHelper(sqlDB))
+
+	// Exec
+	_, err = sqlDB.Exec("INSERT INTO customers (name, updatetime, cust_group) VALUES (:1, :2, :3)", nil, time.Now(), 1)
+	require.ErrorIs(t, err, sqlcommons.CannotSetNullColumn)
+	audit.Close()
+
+	var outcome, command string
+	require.NoError(t, sqlDB.QueryRow("SELECT outcome, command FROM audit_log WHERE kind = 'DML'").Scan(&outcome, &command))
+	require.Equal(t, "failure", outcome)
+	require.Equal(t, "INSERT", command)
+}
+
+func Test_sqlConn_CloseFlushesAuditRecords(t *testing.T) {
+	// Setup
+	file := t.TempDir() + "/audit.db"
+	audit := connector.NewAuditHook(connector.NewTableAuditSink("audit_log"), logger.NewNoLogLogger())
+	sqlProxy := NewSQLProxyBuilder(connector.NewSqlite3Connector(file)).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		WithHooks(audit).
+		Build()
+
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+
+	_, err = sqlDB.Exec(`CREATE TABLE audit_log (event_time TIMESTAMP, identity TEXT, kind TEXT, command TEXT, statement TEXT,
+		args TEXT, rows_affected INT, duration_ms INT, outcome TEXT, error TEXT)`)
+	require.NoError(t, err)
+	require.NoError(t, createTablesHelper(sqlDB))
+
+	// Exec
+	for i := 0; i < 20; i++ {
+		_, err = sqlDB.Exec("INSERT INTO customers_groups (groupname) VALUES (:1)", fmt.Sprintf("group%d", i))
+		require.NoError(t, err)
+	}
+	require.NoError(t, sqlProxy.Close())
+
+	sqlProxy = NewSQLProxyBuilder(connector.NewSqlite3Connector(file)).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		Build()
+	sqlDB, err = sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+
+	var audited int
+	require.NoError(t, sqlDB.QueryRow("SELECT COUNT(*) FROM audit_log WHERE command = 'INSERT'").Scan(&audited))
+	require.Equal(t, 20, audited)
+	require.Zero(t, audit.Dropped())
+}
+
+func Test_sqlConn_PolicyRejectsStatements(t *testing.T) {
+	// Setup
+	sqlProxy := NewSQLProxyBuilder(connector.NewSqlite3Connector(":memory:")).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		WithPolicy(connector.NewPolicyHook().NoDDL().DenyUnfilteredWrites().DenyPattern(`(?i)\bsqlite_master\b`)).
+		Build()
+
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+
+	// Exec
+	_, err = sqlDB.Exec("CREATE TABLE customers_groups (id INTEGER PRIMARY KEY, groupname TEXT NOT NULL)")
+	require.ErrorIs(t, err, sqlerrors.StatementNotAllowed)
+
+	_, err = sqlDB.Exec("DELETE FROM customers_groups")
+	require.ErrorIs(t, err, sqlerrors.StatementNotAllowed)
+
+	_, err = sqlDB.Query("SELECT name FROM sqlite_master")
+	require.ErrorIs(t, err, sqlerrors.StatementNotAllowed)
+
+	var one int
+	require.NoError(t, sqlDB.QueryRow("SELECT 1").Scan(&one))
+}
+
+func Test_sqlConn_ReadOnlyPolicy(t *testing.T) {
+	// Setup
+	sqlProxy := NewSQLProxyBuilder(connector.NewSqlite3Connector(":memory:")).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		WithPolicy(connector.NewPolicyHook().ReadOnly()).
+		Build()
+
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+
+	// Exec
+	_, err = sqlDB.Exec("INSERT INTO customers_groups (groupname) VALUES ('General')")
+	require.ErrorIs(t, err, sqlerrors.StatementNotAllowed)
+
+	_, err = sqlDB.Query("WITH d AS (DELETE FROM customers_groups RETURNING *) SELECT * FROM d")
+	require.ErrorIs(t, err, sqlerrors.StatementNotAllowed)
+
+	_, err = sqlDB.Query("EXPLAIN ANALYZE DELETE FROM customers_groups")
+	require.ErrorIs(t, err, sqlerrors.StatementNotAllowed)
+
+	var one int
+	require.NoError(t, sqlDB.QueryRow("WITH t AS (SELECT 1 AS n) SELECT n FROM t").Scan(&one))
+	require.Equal(t, 1, one)
+
+	rows, err := sqlDB.Query("EXPLAIN QUERY PLAN SELECT 1")
+	require.NoError(t, err)
+	rows.Close()
+
+	kind, command := connector.ClassifyStatement("WITH l AS (SELECT id FROM t FOR NO KEY UPDATE) SELECT * FROM l")
+	require.Equal(t, connector.StatementSelect, kind)
+	require.Equal(t, "SELECT", command)
+	kind, command = connector.ClassifyStatement("EXPLAIN (ANALYZE, BUFFERS) WITH u AS (UPDATE t SET n = 1 RETURNING id) SELECT id FROM u")
+	require.Equal(t, connector.StatementDML, kind)
+	require.Equal(t, "UPDATE", command)
+}
+
+func Test_sqlConn_ClassifyStatement(t *testing.T) {
+	cases := []struct {
+		query   string
+		kind    connector.StatementKind
+		command string
+	}{
+		{"WITH c AS (SELECT q'[it's (UPDATE]' AS v FROM dual) SELECT v FROM c", connector.StatementSelect, "SELECT"},
+		{"WITH c AS (SELECT q'[it's]' AS v FROM dual), d AS (DELETE FROM t RETURNING id) SELECT id FROM d", connector.StatementDML, "DELETE"},
+		{"WITH c AS (SELECT $$it's (UPDATE$$ AS v) SELECT v FROM c", connector.StatementSelect, "SELECT"},
+		{"WITH c AS (SELECT $$it's$$ AS v), d AS (DELETE FROM t RETURNING id) SELECT id FROM d", connector.StatementDML, "DELETE"},
+		{"CREATE FUNCTION f() RETURNS void AS $$ DELETE FROM t $$ LANGUAGE sql", connector.StatementDDL, "CREATE"},
+		{"BEGIN", connector.StatementOther, "BEGIN"},
+		{"BEGIN;", connector.StatementOther, "BEGIN"},
+		{"BEGIN TRANSACTION", connector.StatementOther, "BEGIN"},
+		{"BEGIN IMMEDIATE", connector.StatementOther, "BEGIN"},
+		{"BEGIN ISOLATION LEVEL SERIALIZABLE", connector.StatementOther, "BEGIN"},
+		{"BEGIN DELETE FROM t; END;", connector.StatementPLSQL, "BEGIN"},
+		{"BEGIN\n  NULL;\nEND;", connector.StatementPLSQL, "BEGIN"},
+	}
+	for _, c := range cases {
+		kind, command := connector.ClassifyStatement(c.query)
+		require.Equal(t, c.kind, kind, c.query)
+		require.Equal(t, c.command, command, c.query)
+	}
+}
+
+func Test_sqlConn_BeginReadOnly(t *testing.T) {
+	// Setup
+	sqlProxy := NewSQLProxyBuilder(connector.NewSqlite3Connector(t.TempDir() + "/readonly.db")).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		Build()
+
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+	sqlDB.SetMaxOpenConns(1)
+
+	require.NoError(t, createTablesHelper(sqlDB))
+
+	// Exec
+	tx, err := sqlProxy.BeginReadOnly(context.Background())
+	require.NoError(t, err)
+
+	_, err = tx.Exec("INSERT INTO customers_groups (groupname) VALUES ('General')")
+	require.Error(t, err)
+
+	var count int
+	require.NoError(t, tx.QueryRow("SELECT COUNT(*) FROM customers_groups").Scan(&count))
+	require.NoError(t, tx.Rollback())
+
+	_, err = sqlDB.Exec("INSERT INTO customers_groups (groupname) VALUES ('General')")
+	require.NoError(t, err)
+}
+
+func Test_sqlConn_DefaultQueryTimeout(t *testing.T) {
+	// Setup
+	sqlProxy := NewSQLProxyBuilder(connector.NewSqlite3Connector(":memory:")).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		WithDefaultQueryTimeout(50 * time.Millisecond).
+		WithTimeouts(connector.Timeouts{Exec: 100 * time.Millisecond}).
+		Build()
+
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+
+	endless := "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT x FROM c"
+
+	// Exec
+	var count int64
+	err = sqlDB.QueryRow("SELECT COUNT(*) FROM (" + endless + ")").Scan(&count)
+	require.ErrorIs(t, err, sqlerrors.QueryTimeout)
+
+	_, err = sqlDB.Exec("CREATE TABLE numbers AS " + endless)
+	require.ErrorIs(t, err, sqlerrors.QueryTimeout)
+
+	require.NoError(t, sqlDB.QueryRow("SELECT COUNT(*) FROM (SELECT 1)").Scan(&count))
+	require.Equal(t, int64(1), count)
+}
+
+func Test_sqlConn_CircuitBreaker(t *testing.T) {
+	// Setup
+	var mu sync.Mutex
+	var transitions []string
+	breaker := connector.NewCircuitBreaker(2, time.Minute, 100*time.Millisecond).
+		OnStateChange(func(from connector.BreakerState, to connector.BreakerState) {
+			mu.Lock()
+			defer mu.Unlock()
+			transitions = append(transitions, string(from)+">"+string(to))
+		})
+
+	dir := t.TempDir() + "/missing"
+	sqlProxy := NewSQLProxyBuilder(connector.NewSqlite3Connector(dir + "/breaker.db")).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		WithCircuitBreaker(breaker).
+		Build()
+
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+
+	// Exec
+	for i := 0; i < 2; i++ {
+		_, err = sqlDB.Exec("SELECT 1")
+		require.Error(t, err)
+		require.NotErrorIs(t, err, sqlerrors.CircuitOpen)
+	}
+
+	_, err = sqlDB.Exec("SELECT 1")
+	require.ErrorIs(t, err, sqlerrors.CircuitOpen)
+	require.ErrorIs(t, err, sqlcommons.ConnectionClosed)
+	require.ErrorIs(t, sqlProxy.IsOpen(), sqlerrors.CircuitOpen)
+
+	require.NoError(t, os.Mkdir(dir, 0700))
+	require.Eventually(t, func() bool {
+		return breaker.State() == connector.BreakerClosed
+	}, 2*time.Second, 20*time.Millisecond)
+
+	_, err = sqlDB.Exec("SELECT 1")
+	require.NoError(t, err)
+
+	stats := breaker.Stats()
+	require.Equal(t, uint64(1), stats.Trips)
+	require.Equal(t, uint64(2), stats.Rejected)
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, []string{"closed>open", "open>half-open", "half-open>closed"}, transitions)
+}
+
+func Test_sqlConn_CircuitBreakerIgnoresRejections(t *testing.T) {
+	// Setup
+	breaker := connector.NewCircuitBreaker(1, time.Minute, 50*time.Millisecond).WithProbeTimeout(0)
+	sqlProxy := NewSQLProxyBuilder(connector.NewSqlite3Connector(":memory:")).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		WithCircuitBreaker(breaker).
+		WithPolicy(connector.NewPolicyHook().ReadOnly()).
+		Build()
+
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+	sqlDB.SetMaxOpenConns(1)
+
+	_, err = sqlDB.Exec("SELECT 1")
+	require.NoError(t, err)
+	breaker.Report(io.ErrUnexpectedEOF)
+	require.Equal(t, connector.BreakerOpen, breaker.State())
+
+	// Exec
+	time.Sleep(60 * time.Millisecond)
+	_, err = sqlDB.Exec("DELETE FROM customers")
+	require.ErrorIs(t, err, sqlerrors.StatementNotAllowed)
+	require.Equal(t, connector.BreakerHalfOpen, breaker.State())
+
+	time.Sleep(60 * time.Millisecond)
+	_, err = sqlDB.Exec("SELECT 1")
+	require.NoError(t, err)
+	require.Equal(t, connector.BreakerClosed, breaker.State())
+}
+
+func Test_sqlConn_CircuitBreakerNativeErrors(t *testing.T) {
+	// Setup
+	statementFailed := connector.Fail(&pgconn.PgError{Severity: "ERROR", Code: "42P01", Message: "relation does not exist"})
+	faults := connector.NewFaultInjector(connector.NewSqlite3Connector(":memory:"), statementFailed)
+	breaker := connector.NewCircuitBreaker(2, time.Minute, time.Minute)
+	sqlProxy := NewSQLProxyBuilder(faults).
+		WithAdapter(adapter.NewPostgresAdapter("")).
+		WithLogger(logger.NewNoLogLogger()).
+		WithCircuitBreaker(breaker).
+		Build()
+
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+
+	// Exec
+	for i := 0; i < 2; i++ {
+		_, err = sqlDB.Exec("SELECT 1")
+		require.ErrorContains(t, err, "Unhandled PostgreSQL error. Code:[42P01]")
+	}
+	require.Equal(t, connector.BreakerClosed, breaker.State())
+
+	faults.Clear()
+	faults.Inject(connector.Fail(&pgconn.PgError{Severity: "FATAL", Code: "57P01", Message: "terminating connection due to administrator command"}))
+	for i := 0; i < 2; i++ {
+		_, err = sqlDB.Exec("SELECT 1")
+		require.ErrorContains(t, err, "Unhandled PostgreSQL error. Code:[57P01]")
+	}
+	require.Equal(t, connector.BreakerOpen, breaker.State())
+
+	_, err = sqlDB.Exec("SELECT 1")
+	require.ErrorIs(t, err, sqlerrors.CircuitOpen)
+}
+
+func Test_sqlConn_AdmissionControl(t *testing.T) {
+	// Setup
+	admission := connector.NewAdmissionHook(1, 50*time.Millisecond)
+	sqlProxy := NewSQLProxyBuilder(connector.NewSqlite3Connector(":memory:")).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		WithHooks(admission).
+		Build()
+
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+
+	held := &connector.QueryEvent{Query: "SELECT 1"}
+	require.NoError(t, admission.Before(context.Background(), held))
+
+	// Exec
+	_, err = sqlDB.Exec("SELECT 1")
+	require.ErrorIs(t, err, sqlerrors.AdmissionRejected)
+
+	admission.After(context.Background(), held)
+	_, err = sqlDB.Exec("SELECT 1")
+	require.NoError(t, err)
+
+	stats := admission.Stats()
+	require.Equal(t, uint64(2), stats.Admitted)
+	require.Equal(t, uint64(1), stats.Rejected)
+	require.Equal(t, 0, stats.InFlight)
+}
+
+func Test_sqlConn_AdmissionPriorities(t *testing.T) {
+	// Setup
+	admission := connector.NewAdmissionHook(1, 5*time.Second)
+	held := &connector.QueryEvent{Query: "SELECT 1"}
+	require.NoError(t, admission.Before(context.Background(), held))
+
+	admitted := make(chan string, 2)
+	wait := func(ctx context.Context, name string, event *connector.QueryEvent) {
+		if err := admission.Before(ctx, event); err == nil {
+			admitted <- name
+		}
+	}
+	batch := &connector.QueryEvent{Query: "SELECT 2"}
+	go wait(connector.WithPriority(context.Background(), connector.PriorityBatch), "batch", batch)
+	require.Eventually(t, func() bool { return admission.Stats().Queued == 1 }, time.Second, time.Millisecond)
+	interactive := &connector.QueryEvent{Query: "SELECT 3"}
+	go wait(context.Background(), "interactive", interactive)
+	require.Eventually(t, func() bool { return admission.Stats().Queued == 2 }, time.Second, time.Millisecond)
+
+	// Exec
+	admission.After(context.Background(), held)
+	require.Equal(t, "interactive", <-admitted)
+	admission.After(context.Background(), interactive)
+	require.Equal(t, "batch", <-admitted)
+	admission.After(context.Background(), batch)
+	require.Equal(t, 0, admission.Stats().InFlight)
+}
+
+func Test_sqlConn_SelectIntoTaggedStructs(t *testing.T) {
+	// Setup
+	sqlProxy := NewSQLProxyBuilder(connector.NewSqlite3Connector(":memory:")).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		Build()
+
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+
+	require.NoError(t, createTablesHelper(sqlDB))
+	require.NoError(t, insertDataHelper(sqlDB))
+	ctx := context.Background()
+
+	// Exec
+	customers, err := Select[Customers](ctx, sqlDB, "SELECT * FROM customers ORDER BY id")
+	require.NoError(t, err)
+	require.Len(t, customers, 3)
+	require.Equal(t, "Juan", customers[0].Name)
+	require.False(t, customers[0].Age.Valid)
+	require.Equal(t, int64(99), customers[2].Age.Int64)
+
+	_, err = Select[Customers](WithScanMode(ctx, ScanStrict), sqlDB, "SELECT * FROM customers")
+	require.ErrorIs(t, err, sqlerrors.UnmappedColumn)
+
+	type Group struct {
+		GroupName string `db:"groupname"`
+	}
+	type customerWithGroup struct {
+		*Group
+		Id  int    `db:"id"`
+		Age *int64 `db:"age"`
+	}
+	pablo, err := Get[customerWithGroup](ctx, sqlDB, `SELECT c.id AS ID, c.age AS AGE, g.groupname AS GROUPNAME
+		FROM customers c JOIN customers_groups g ON g.id = c.cust_group WHERE c.name = :1`, "Pablo")
+	require.NoError(t, err)
+	require.Equal(t, "General", pablo.GroupName)
+	require.Equal(t, int64(99), *pablo.Age)
+
+	count, err := Get[int](ctx, sqlDB, "SELECT COUNT(*) FROM customers")
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+
+	_, err = Get[Customers](ctx, sqlDB, "SELECT * FROM customers WHERE name = :1", "Nadie")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func Test_sqlConn_IterStreamsRows(t *testing.T) {
+	// Setup
+	sqlProxy := NewSQLProxyBuilder(connector.NewSqlite3Connector(":memory:")).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		Build()
+
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+
+	require.NoError(t, createTablesHelper(sqlDB))
+	require.NoError(t, insertDataHelper(sqlDB))
+
+	// Exec
+	var names []string
+	Iter[Customers](context.Background(), sqlDB, "SELECT * FROM customers WHERE cust_group = :1 ORDER BY id", 1)(func(c Customers, err error) bool {
+		require.NoError(t, err)
+		names = append(names, c.Name)
+		return len(names) < 2
+	})
+	require.Equal(t, []string{"Juan", "Pedro"}, names)
+	require.Equal(t, 0, sqlDB.Stats().InUse)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var iterErr error
+	Iter[string](ctx, sqlDB, "SELECT name FROM customers")(func(name string, err error) bool {
+		iterErr = err
+		cancel()
+		return true
+	})
+	require.ErrorIs(t, iterErr, context.Canceled)
+	require.Equal(t, 0, sqlDB.Stats().InUse)
+
+	cursor, err := NewCursor[int64](context.Background(), sqlDB, "SELECT age FROM customers WHERE age IS NOT NULL")
+	require.NoError(t, err)
+	defer cursor.Close()
+	require.True(t, cursor.Next())
+	require.Equal(t, int64(99), cursor.Value())
+	require.False(t, cursor.Next())
+	require.NoError(t, cursor.Err())
+}
+
+func Test_sqlConn_BulkInsert(t *testing.T) {
+	// Setup
+	sqlProxy := NewSQLProxyBuilder(connector.NewSqlite3Connector(":memory:")).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		Build()
+
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+
+	require.NoError(t, createTablesHelper(sqlDB))
+	require.NoError(t, insertDataHelper(sqlDB))
+	require.Equal(t, SQLite3, sqlProxy.Engine())
+
+	rows := make([][]interface{}, 2500)
+	for i := range rows {
+		rows[i] = []interface{}{fmt.Sprintf("c%d", i), time.Now(), nil, 1}
+	}
+
+	// Exec
+	inserted, err := BulkInsert(context.Background(), sqlProxy, "customers", []string{"name", "updatetime", "age", "cust_group"}, rows)
+	require.NoError(t, err)
+	require.Equal(t, int64(2500), inserted)
+
+	for i := range rows {
+		rows[i][0] = fmt.Sprintf("d%d", i)
+	}
+	rows[1800][0] = "Juan"
+	_, err = BulkInsert(context.Background(), sqlProxy, "customers", []string{"name", "updatetime", "age", "cust_group"}, rows)
+	var bulkErr *sqlerrors.BulkInsertError
+	require.ErrorAs(t, err, &bulkErr)
+	require.Equal(t, 1800, bulkErr.Row)
+	require.ErrorIs(t, err, sqlcommons.UniqueConstraintViolation)
+
+	count, err := Get[int](context.Background(), sqlDB, "SELECT COUNT(*) FROM customers")
+	require.NoError(t, err)
+	require.Equal(t, 2503, count)
+}
+
+func Test_sqlConn_Upsert(t *testing.T) {
+	// Setup
+	sqlProxy := NewSQLProxyBuilder(connector.NewSqlite3Connector(":memory:")).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		Build()
+
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+
+	require.NoError(t, createTablesHelper(sqlDB))
+	require.NoError(t, insertDataHelper(sqlDB))
+	ctx := context.Background()
+
+	type customer struct {
+		Id    int64  `db:"id"`
+		Name  string `db:"name"`
+		Age   *int64 `db:"age"`
+		Group int    `db:"cust_group"`
+	}
+	age := int64(40)
+
+	// Exec
+	result, err := UpsertStruct("customers", []string{"name"}, customer{Name: "Juan", Age: &age, Group: 1}).
+		Omit("id").Returning("id").Exec(ctx, sqlProxy)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), result.RowsAffected)
+	require.Equal(t, int64(1), result.Keys["id"])
+
+	juan, err := Get[customer](ctx, sqlDB, "SELECT * FROM customers WHERE name = :1", "Juan")
+	require.NoError(t, err)
+	require.Equal(t, age, *juan.Age)
+
+	result, err = Upsert("customers", []string{"name"}, map[string]interface{}{"name": "Ana", "cust_group": 1}).
+		DoNothing().Exec(ctx, sqlProxy)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), result.RowsAffected)
+
+	result, err = Upsert("customers", []string{"name"}, map[string]interface{}{"name": "Ana", "cust_group": 1}).
+		DoNothing().Exec(ctx, sqlProxy)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), result.RowsAffected)
+
+	query, _, err := Upsert("customers", []string{"name"}, map[string]interface{}{"name": "Ana", "age": 30}).ToSQL(Oracle)
+	require.NoError(t, err)
+	require.Equal(t, "MERGE INTO customers t USING (SELECT :1 AS age, :2 AS name FROM DUAL) s ON (t.name = s.name) "+
+		"WHEN MATCHED THEN UPDATE SET t.age = s.age WHEN NOT MATCHED THEN INSERT (age, name) VALUES (s.age, s.name)", query)
+
+	query, _, err = Upsert("customers", []string{"name"}, map[string]interface{}{"name": "Ana", "age": 30}).Returning("id").ToSQL(PostgreSQL)
+	require.NoError(t, err)
+	require.Equal(t, "INSERT INTO customers (age, name) VALUES ($1, $2) ON CONFLICT (name) DO UPDATE SET age = EXCLUDED.age RETURNING id", query)
+}
+
+func Test_sqlConn_Paginate(t *testing.T) {
+	// Setup
+	sqlProxy := NewSQLProxyBuilder(connector.NewSqlite3Connector(":memory:")).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		Build()
+
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+
+	require.NoError(t, createTablesHelper(sqlDB))
+	require.NoError(t, insertDataHelper(sqlDB))
+	_, err = sqlDB.Exec("INSERT INTO customers (name, cust_group) VALUES (:1, 1), (:2, 1)", "Ana", "Luis")
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	type customer struct {
+		Id   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+	names := func(items []customer) []string {
+		var names []string
+		for _, item := range items {
+			names = append(names, item.Name)
+		}
+		return names
+	}
+
+	// Exec
+	offset := NewPaginator("SELECT id, name FROM customers WHERE cust_group = :1", 2, "name").WithArgs(1)
+	var pages [][]string
+	token := ""
+	for {
+		page, err := Fetch[customer](ctx, sqlProxy, offset, token)
+		require.NoError(t, err)
+		pages = append(pages, names(page.Items))
+		if token = page.Next; token == "" {
+			break
+		}
+	}
+	require.Equal(t, [][]string{{"Ana", "Juan"}, {"Luis", "Pablo"}, {"Pedro"}}, pages)
+
+	keyset := NewPaginator("SELECT id, name FROM customers", 2, "name DESC", "id").Keyset()
+	page, err := Fetch[customer](ctx, sqlProxy, keyset, "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"Pedro", "Pablo"}, names(page.Items))
+
+	_, err = sqlDB.Exec("DELETE FROM customers WHERE name = :1", "Pedro")
+	require.NoError(t, err)
+	page, err = Fetch[customer](ctx, sqlProxy, keyset, page.Next)
+	require.NoError(t, err)
+	require.Equal(t, []string{"Luis", "Juan"}, names(page.Items))
+
+	_, err = Fetch[customer](ctx, sqlProxy, offset, page.Next)
+	require.ErrorIs(t, err, sqlerrors.InvalidPageToken)
+
+	query, args, err := keyset.ToSQL(Oracle, page.Next)
+	require.NoError(t, err)
+	require.Equal(t, "SELECT * FROM (SELECT id, name FROM customers) p WHERE (p.name < :1) OR (p.name = :2 AND p.id > :3) "+
+		"ORDER BY p.name DESC, p.id OFFSET 0 ROWS FETCH NEXT 3 ROWS ONLY", query)
+	require.Equal(t, []interface{}{"Juan", "Juan", int64(1)}, args)
+}
+
+func Test_sqlConn_InsertReturning(t *testing.T) {
+	// Setup
+	sqlProxy := NewSQLProxyBuilder(connector.NewSqlite3Connector(":memory:")).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		Build()
+
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+
+	require.NoError(t, createTablesHelper(sqlDB))
+	require.NoError(t, insertDataHelper(sqlDB))
+	ctx := context.Background()
+
+	// Exec
+	keys, err := sqlProxy.InsertReturning(ctx, "INSERT INTO customers (name, age, cust_group) VALUES (:1, :2, :3);",
+		[]interface{}{"Ana", 30, 1}, "id", "age")
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"id": int64(4), "age": int64(30)}, keys)
+
+	_, err = sqlProxy.InsertReturning(ctx, "INSERT INTO customers (name, cust_group) VALUES (:1, :2)", []interface{}{"Ana", 1}, "id")
+	require.ErrorIs(t, err, sqlcommons.UniqueConstraintViolation)
+	var id int
+	var name string
+	require.NoError(t, sqlProxy.InsertReturningInto(ctx, "INSERT INTO customers (name, cust_group) VALUES (:1, :2)",
+		[]interface{}{"007", 1}, []string{"id", "name"}, &id, &name))
+	require.Equal(t, 5, id)
+	require.Equal(t, "007", name)
+
+	require.Error(t, sqlProxy.InsertReturningInto(ctx, "INSERT INTO customers (name, cust_group) VALUES (:1, :2)",
+		[]interface{}{"Eva", 1}, []string{"id", "name"}, &id))
+}
+
+func Test_sqlConn_MockSequence(t *testing.T) {
+	// Setup
+	mock := connector.NewMockSQLConnector(true).WithEngine(string(Oracle))
+	sqlProxy := NewSQLProxyBuilder(mock).
+		WithAdapter(adapter.NewOracleAdapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		Build()
+
+	_, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+	require.Equal(t, Oracle, sqlProxy.Engine())
+
+	mock.PatchSequence("customers_seq", 10, 11)
+	mock.PatchSequenceError("customers_seq", sqlcommons.ConnectionClosed)
+	ctx := context.Background()
+
+	// Exec
+	id, err := sqlProxy.GetNextSequenceValue(ctx, "customers_seq")
+	require.NoError(t, err)
+	require.Equal(t, int64(10), id)
+
+	id, err = sqlProxy.GetNextSequenceValue(ctx, "customers_seq")
+	require.NoError(t, err)
+	require.Equal(t, int64(11), id)
+
+	_, err = sqlProxy.GetNextSequenceValue(ctx, "customers_seq")
+	require.ErrorIs(t, err, sqlcommons.NextValueFailed)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func Test_sqlConn_RecordAndReplay(t *testing.T) {
+	// Setup
+	fixture := t.TempDir() + "/customers.json"
+	setup := func(sqlDB *sql.DB) error {
+		if err := createTablesHelper(sqlDB); err != nil {
+			return err
+		}
+		// Unlike insertDataHelper's, the arguments must be the same on replay
+		_, err := sqlDB.Exec("INSERT INTO customers_groups (groupname) VALUES ('General')")
+		if err == nil {
+			_, err = sqlDB.Exec("INSERT INTO customers (name, age, cust_group) VALUES (:1, NULL, 1), (:2, NULL, 1), (:3, 99, 1)", "Juan", "Pedro", "Pablo")
+		}
+		return err
+	}
+	scenario := func(sqlDB *sql.DB) ([]string, error) {
+		ctx := context.Background()
+		tx, err := sqlDB.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO customers (name, age, cust_group) VALUES (:1, :2, :3)", "Ana", 30, 1); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		names, err := Select[string](ctx, sqlDB, "SELECT name FROM customers WHERE age IS NULL OR age < :1 ORDER BY name", 50)
+		if err != nil {
+			return nil, err
+		}
+		_, err = sqlDB.ExecContext(ctx, "INSERT INTO customers (name, cust_group) VALUES (:1, :2)", "Ana", 1)
+		return names, err
+	}
+
+	recording := NewSQLProxyBuilder(connector.NewRecordingConnector(connector.NewSqlite3Connector(":memory:"), fixture)).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		Build()
+	sqlDB, err := recording.Open()
+	require.NoError(t, err)
+	require.NoError(t, setup(sqlDB))
+	_, err = os.Stat(fixture)
+	require.NoError(t, err)
+
+	recordedNames, recordedErr := scenario(sqlDB)
+	require.ErrorIs(t, recordedErr, sqlcommons.UniqueConstraintViolation)
+	require.Equal(t, []string{"Ana", "Juan", "Pedro"}, recordedNames)
+	recording.Close()
+
+	// Exec
+	replay := NewSQLProxyBuilder(connector.NewReplayConnector(fixture)).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		Build()
+	sqlDB, err = replay.Open()
+	require.NoError(t, err)
+	defer replay.Close()
+	require.Equal(t, SQLite3, replay.Engine())
+
+	require.NoError(t, setup(sqlDB))
+	names, err := scenario(sqlDB)
+	require.ErrorIs(t, err, sqlcommons.UniqueConstraintViolation)
+	require.Equal(t, recordedNames, names)
+
+	_, err = sqlDB.Exec("DELETE FROM customers")
+	require.ErrorIs(t, err, sqlerrors.UnexpectedStatement)
+}
+
+func Test_sqlConn_RecordAndReplayHashesSecrets(t *testing.T) {
+	// Setup
+	fixture := t.TempDir() + "/users.json"
+	scenario := func(sqlDB *sql.DB, token string) (string, error) {
+		if _, err := sqlDB.Exec("CREATE TABLE users (name TEXT, password TEXT, token TEXT)"); err != nil {
+			return "", err
+		}
+		if _, err := sqlDB.Exec("INSERT INTO users (name, password, token) VALUES (:1, :2, :3)", "Juan", "s3cr3t", Secret("abc123")); err != nil {
+			return "", err
+		}
+		var name string
+		err := sqlDB.QueryRow("SELECT name FROM users WHERE token = :1", Secret(token)).Scan(&name)
+		return name, err
+	}
+
+	recording := NewSQLProxyBuilder(connector.NewRecordingConnector(connector.NewSqlite3Connector(":memory:"), fixture)).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		Build()
+	sqlDB, err := recording.Open()
+	require.NoError(t, err)
+	name, err := scenario(sqlDB, "abc123")
+	require.NoError(t, err)
+	require.Equal(t, "Juan", name)
+	recording.Close()
+
+	data, err := os.ReadFile(fixture)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "s3cr3t")
+	require.NotContains(t, string(data), "abc123")
+	require.Contains(t, string(data), `"sha256"`)
+
+	// Exec
+	for token, expectedErr := range map[string]error{"abc123": nil, "xyz789": sqlerrors.UnexpectedStatement} {
+		replay := NewSQLProxyBuilder(connector.NewReplayConnector(fixture)).
+			WithAdapter(adapter.NewSQLite3Adapter()).
+			WithLogger(logger.NewNoLogLogger()).
+			Build()
+		sqlDB, err = replay.Open()
+		require.NoError(t, err)
+
+		name, err = scenario(sqlDB, token)
+		if expectedErr == nil {
+			require.NoError(t, err)
+			require.Equal(t, "Juan", name)
+		} else {
+			require.ErrorIs(t, err, expectedErr)
+		}
+		replay.Close()
+	}
+}
+
+func Test_sqlConn_FaultInjection(t *testing.T) {
+	// Setup
+	uniqueViolation := connector.UniqueViolation().OnQuery("^INSERT INTO customers_groups").OnCall(2)
+	dropConnection := connector.DropConnection().OnQuery("^UPDATE").OnCall(1)
+	failCommit := connector.FailCommit().OnCall(1)
+	failPing := connector.FailPing().OnCall(1)
+	latency := connector.Latency(50 * time.Millisecond).OnQuery("^SELECT count")
+
+	sqlProxy := NewSQLProxyBuilder(connector.NewFaultInjector(connector.NewSqlite3Connector(t.TempDir()+"/faults.db"),
+		uniqueViolation, dropConnection, failCommit, failPing, latency)).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		Build()
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+	require.NoError(t, createTablesHelper(sqlDB))
+
+	// Exec
+	_, err = sqlDB.Exec("INSERT INTO customers_groups (groupname) VALUES ('General')")
+	require.NoError(t, err)
+	_, err = sqlDB.Exec("INSERT INTO customers_groups (groupname) VALUES ('Premium')")
+	require.ErrorIs(t, err, sqlcommons.UniqueConstraintViolation)
+
+	_, err = sqlDB.Exec("UPDATE customers_groups SET groupname = 'Main' WHERE id = 1")
+	require.ErrorIs(t, err, sqlerrors.InjectedFault)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	var groupname string
+	require.NoError(t, sqlDB.QueryRow("SELECT groupname FROM customers_groups WHERE id = 1").Scan(&groupname))
+	require.Equal(t, "Main", groupname)
+
+	tx, err := sqlDB.Begin()
+	require.NoError(t, err)
+	_, err = tx.Exec("INSERT INTO customers_groups (groupname) VALUES ('Lost')")
+	require.NoError(t, err)
+	require.ErrorIs(t, tx.Commit(), sqlerrors.InjectedFault)
+
+	start := time.Now()
+	var count int
+	require.NoError(t, sqlDB.QueryRow("SELECT count(*) FROM customers_groups").Scan(&count))
+	require.Equal(t, 1, count)
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	require.NoError(t, sqlProxy.IsOpen())
+	require.NoError(t, sqlProxy.db.QueryRow("SELECT groupname FROM customers_groups WHERE id = 1").Scan(&groupname))
+	require.Equal(t, "Main", groupname)
+
+	for _, fault := range []*connector.Fault{uniqueViolation, dropConnection, failCommit, failPing, latency} {
+		require.Equal(t, 1, fault.Injected())
+	}
+}
+
+func Test_sqlConn_FaultProbability(t *testing.T) {
+	// Setup
+	run := func(seed int64) int {
+		fault := connector.Fail(errors.New("flaky")).WithProbability(0.5)
+		sqlProxy := NewSQLProxyBuilder(connector.NewFaultInjector(connector.NewSqlite3Connector(":memory:"), fault).WithSeed(seed)).
+			WithAdapter(adapter.NewSQLite3Adapter()).
+			WithLogger(logger.NewNoLogLogger()).
+			Build()
+		sqlDB, err := sqlProxy.Open()
+		require.NoError(t, err)
+		defer sqlProxy.Close()
+
+		failed := 0
+		for i := 0; i < 100; i++ {
+			if _, err := sqlDB.Exec("SELECT 1"); err != nil {
+				failed++
+			}
+		}
+		require.Equal(t, failed, fault.Injected())
+		return failed
+	}
+
+	// Exec
+	failed := run(42)
+	require.Greater(t, failed, 25)
+	require.Less(t, failed, 75)
+	require.Equal(t, failed, run(42))
+}
+
+// Test_sqlConn_Sqlite3Driver runs on the pure-Go driver when built without cgo or with the sqlite_purego build tag.
+func Test_sqlConn_Sqlite3Driver(t *testing.T) {
+	// Setup
+	sqlProxy := NewSQLProxyBuilder(connector.NewSqlite3Connector(":memory:?_foreign_keys=on")).
+		WithAdapter(adapter.NewSQLite3Adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		Build()
+
+	sqlDB, err := sqlProxy.Open()
+	require.NoError(t, err)
+	defer sqlProxy.Close()
+	sqlDB.SetMaxOpenConns(1)
+
+	require.NoError(t, createTablesHelper(sqlDB))
+	require.NoError(t, insertDataHelper(sqlDB))
+
+	// Exec
+	updatetime := time.Date(2024, 2, 29, 13, 45, 30, 0, time.UTC)
+	_, err = sqlDB.Exec("INSERT INTO customers (name, updatetime, age, cust_group) VALUES (:1, :2, :3, :4)", "Maria", updatetime, 30, 1)
+	require.NoError(t, err)
+
+	var stored time.Time
+	require.NoError(t, sqlDB.QueryRow("SELECT updatetime FROM customers WHERE name = :1", "Maria").Scan(&stored))
+	require.True(t, updatetime.Equal(stored))
+
+	_, err = sqlDB.Exec("INSERT INTO customers (name, updatetime, age, cust_group) VALUES (:1, :2, :3, :4)", "Juan", time.Now(), nil, 1)
+	require.ErrorIs(t, err, sqlcommons.UniqueConstraintViolation)
+
+	_, err = sqlDB.Exec("INSERT INTO customers (name, updatetime, age, cust_group) VALUES (:1, :2, :3, :4)", nil, time.Now(), nil, 1)
+	require.ErrorIs(t, err, sqlcommons.CannotSetNullColumn)
+
+	_, err = sqlDB.Exec("UPDATE customers SET cust_group = :1 WHERE name = :2", 2, "Maria")
+	require.ErrorIs(t, err, sqlcommons.IntegrityConstraintViolation)
+}
+
 func createTablesHelper(sqlClient *sql.DB) error {
 
 	if _, err := sqlClient.Exec(`CREATE TABLE IF NOT EXISTS customers_groups (
@@ -1,5 +1,7 @@
 package sqldb
 
+import "github.com/cdleo/go-sqldb/connector"
+
 type DBEngine string
 
 const (
@@ -31,3 +33,8 @@ var SQLSintaxTranslators = []SQLSintaxTranslator{
 	ToPostgreSQL,
 	ToSQLite3,
 }
+
+// Secret marks a bind argument as sensitive, so its value is never written to the query logs.
+func Secret(value interface{}) connector.SecretArg {
+	return connector.NewSecretArg(value)
+}
@@ -0,0 +1,290 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/cdleo/go-commons/sqlcommons"
+	"github.com/cdleo/go-sqldb/connector"
+	"github.com/cdleo/go-sqldb/fingerprint"
+	"github.com/cdleo/go-sqldb/sqlerrors"
+)
+
+type Page[T any] struct {
+	Items []T
+	// Next is the continuation token of the following page, empty on the last one
+	Next string
+}
+
+// Paginator pages the results of a base query (with no ORDER BY of its own), ordered by the given columns
+// (e.g. "id" or "created DESC"), by offset or by keyset (seeking past the last row, for which the ordering
+// columns must be selected by the base query, and be unique together).
+type Paginator struct {
+	query   string
+	args    []interface{}
+	orderBy []orderColumn
+	size    int
+	keyset  bool
+}
+
+type orderColumn struct {
+	name string
+	desc bool
+}
+
+type pageToken struct {
+	Query  string       `json:"q"`
+	Offset int          `json:"o,omitempty"`
+	Keys   []tokenValue `json:"k,omitempty"`
+}
+
+type tokenValue struct {
+	Type  string `json:"t"`
+	Value string `json:"v"`
+}
+
+func NewPaginator(query string, pageSize int, orderBy ...string) *Paginator {
+
+	p := &Paginator{query: query, size: pageSize}
+	for _, column := range orderBy {
+		fields := strings.Fields(column)
+		if len(fields) == 0 {
+			continue
+		}
+		p.orderBy = append(p.orderBy, orderColumn{
+			name: fields[0],
+			desc: len(fields) > 1 && strings.EqualFold(fields[1], "DESC"),
+		})
+	}
+	return p
+}
+
+func (p *Paginator) WithArgs(args ...interface{}) *Paginator {
+	p.args = args
+	return p
+}
+
+// Keyset pages by seeking past the last row of the previous page, rather than skipping the previous rows.
+func (p *Paginator) Keyset() *Paginator {
+	p.keyset = true
+	return p
+}
+
+// ToSQL returns the statement fetching the page after the token (the first one, if empty) for the engine,
+// and its bind arguments. It fetches a row more than the page size, telling whether another page follows.
+func (p *Paginator) ToSQL(engine DBEngine, token string) (string, []interface{}, error) {
+
+	if len(p.orderBy) == 0 || p.size <= 0 {
+		return "", nil, fmt.Errorf("pagination needs ordering columns and a page size")
+	}
+	if engine != Oracle && engine != PostgreSQL && engine != SQLite3 {
+		return "", nil, sqlcommons.DBNotSupported
+	}
+
+	state, err := p.decode(token)
+	if err != nil {
+		return "", nil, err
+	}
+
+	args := append([]interface{}(nil), p.args...)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "SELECT * FROM (%s) p", p.query)
+	if len(state.Keys) > 0 {
+		var conditions []string
+		for i, column := range p.orderBy {
+			var terms []string
+			for j, previous := range p.orderBy[:i] {
+				args = append(args, state.Keys[j])
+				terms = append(terms, fmt.Sprintf("p.%s = %s", previous.name, connector.Placeholder(string(engine), len(args))))
+			}
+			operator := ">"
+			if column.desc {
+				operator = "<"
+			}
+			args = append(args, state.Keys[i])
+			terms = append(terms, fmt.Sprintf("p.%s %s %s", column.name, operator, connector.Placeholder(string(engine), len(args))))
+			conditions = append(conditions, "("+strings.Join(terms, " AND ")+")")
+		}
+		sb.WriteString(" WHERE " + strings.Join(conditions, " OR "))
+	}
+
+	order := make([]string, len(p.orderBy))
+	for i, column := range p.orderBy {
+		order[i] = "p." + column.name
+		if column.desc {
+			order[i] += " DESC"
+		}
+	}
+	sb.WriteString(" ORDER BY " + strings.Join(order, ", "))
+
+	if engine == Oracle {
+		fmt.Fprintf(&sb, " OFFSET %d ROWS FETCH NEXT %d ROWS ONLY", state.Offset, p.size+1)
+	} else {
+		fmt.Fprintf(&sb, " LIMIT %d OFFSET %d", p.size+1, state.Offset)
+	}
+	return sb.String(), args, nil
+}
+
+// Fetch returns the page after the token (the first one, if empty), scanned into T as Get does.
+func Fetch[T any](ctx context.Context, proxy *SQLProxy, p *Paginator, token string) (Page[T], error) {
+
+	if err := proxy.IsOpen(); err != nil {
+		return Page[T]{}, sqlcommons.ConnectionClosed
+	}
+
+	query, args, err := p.ToSQL(proxy.Engine(), token)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	items, err := Select[T](ctx, proxy.db, query, args...)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	if len(items) <= p.size {
+		return Page[T]{Items: items}, nil
+	}
+
+	items = items[:p.size]
+	state, _ := p.decode(token)
+	next := pageToken{Query: fingerprint.Fingerprint(p.query)}
+	if p.keyset {
+		if next.Keys, err = p.keysOf(items[len(items)-1]); err != nil {
+			return Page[T]{}, err
+		}
+	} else {
+		next.Offset = state.Offset + p.size
+	}
+	return Page[T]{Items: items, Next: encodeToken(next)}, nil
+}
+
+type decodedToken struct {
+	Offset int
+	Keys   []interface{}
+}
+
+func (p *Paginator) decode(token string) (decodedToken, error) {
+
+	if token == "" {
+		return decodedToken{}, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return decodedToken{}, fmt.Errorf("%w: %v", sqlerrors.InvalidPageToken, err)
+	}
+	var state pageToken
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return decodedToken{}, fmt.Errorf("%w: %v", sqlerrors.InvalidPageToken, err)
+	}
+	if state.Query != fingerprint.Fingerprint(p.query) {
+		return decodedToken{}, fmt.Errorf("%w: issued for another query", sqlerrors.InvalidPageToken)
+	}
+	if p.keyset != (len(state.Keys) > 0) || (p.keyset && len(state.Keys) != len(p.orderBy)) {
+		return decodedToken{}, fmt.Errorf("%w: issued for another pagination", sqlerrors.InvalidPageToken)
+	}
+
+	decoded := decodedToken{Offset: state.Offset}
+	for _, key := range state.Keys {
+		value, err := key.decode()
+		if err != nil {
+			return decodedToken{}, fmt.Errorf("%w: %v", sqlerrors.InvalidPageToken, err)
+		}
+		decoded.Keys = append(decoded.Keys, value)
+	}
+	return decoded, nil
+}
+
+// keysOf returns the values of the ordering columns of an item.
+func (p *Paginator) keysOf(item interface{}) ([]tokenValue, error) {
+
+	value := reflect.Indirect(reflect.ValueOf(item))
+	var fields *structFields
+	if !isScalar(value.Type()) {
+		fields = fieldsOf(value.Type())
+	}
+
+	keys := make([]tokenValue, len(p.orderBy))
+	for i, column := range p.orderBy {
+		key := value
+		if fields != nil {
+			index, ok := fields.byName[strings.ToLower(column.name)]
+			if !ok {
+				return nil, fmt.Errorf("%w: ordering column [%s] has no field in %s", sqlerrors.UnmappedColumn, column.name, value.Type())
+			}
+			if key, ok = fieldValue(value, index); !ok {
+				return nil, fmt.Errorf("%w: ordering column [%s] is nil", sqlerrors.UnmappedColumn, column.name)
+			}
+		} else if len(p.orderBy) > 1 {
+			return nil, fmt.Errorf("%w: %d ordering columns for %s", sqlerrors.UnmappedColumn, len(p.orderBy), value.Type())
+		}
+
+		var err error
+		if keys[i], err = encodeValue(key.Interface()); err != nil {
+			return nil, fmt.Errorf("ordering column [%s]: %w", column.name, err)
+		}
+	}
+	return keys, nil
+}
+
+func encodeToken(token pageToken) string {
+	raw, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// encodeValue keeps the type of a key in the token, so it is bound back as such.
+func encodeValue(value interface{}) (tokenValue, error) {
+
+	converted, err := driver.DefaultParameterConverter.ConvertValue(value)
+	if err != nil {
+		return tokenValue{}, err
+	}
+	switch v := converted.(type) {
+	case int64:
+		return tokenValue{"int", fmt.Sprint(v)}, nil
+	case float64:
+		return tokenValue{"float", fmt.Sprint(v)}, nil
+	case bool:
+		return tokenValue{"bool", fmt.Sprint(v)}, nil
+	case string:
+		return tokenValue{"string", v}, nil
+	case []byte:
+		return tokenValue{"bytes", base64.StdEncoding.EncodeToString(v)}, nil
+	case time.Time:
+		return tokenValue{"time", v.Format(time.RFC3339Nano)}, nil
+	default:
+		return tokenValue{}, fmt.Errorf("unsupported key value %T", converted)
+	}
+}
+
+func (v tokenValue) decode() (interface{}, error) {
+
+	var value interface{}
+	var err error
+	switch v.Type {
+	case "int":
+		var i int64
+		_, err = fmt.Sscan(v.Value, &i)
+		value = i
+	case "float":
+		var f float64
+		_, err = fmt.Sscan(v.Value, &f)
+		value = f
+	case "bool":
+		value = v.Value == "true"
+	case "string":
+		value = v.Value
+	case "bytes":
+		value, err = base64.StdEncoding.DecodeString(v.Value)
+	case "time":
+		value, err = time.Parse(time.RFC3339Nano, v.Value)
+	default:
+		err = fmt.Errorf("unknown key type [%s]", v.Type)
+	}
+	return value, err
+}
@@ -3,20 +3,50 @@ package sqldb
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"io"
 	"time"
 
 	"github.com/cdleo/go-commons/logger"
 	"github.com/cdleo/go-commons/sqlcommons"
+	"github.com/cdleo/go-sqldb/connector"
+	"github.com/cdleo/go-sqldb/sqlerrors"
 )
 
 type SQLProxy struct {
 	connector  sqlcommons.SQLConnector
 	translator sqlcommons.SQLAdapter
 	logger     logger.Logger
+	hooks      []connector.Hook
+	policy     *connector.PolicyHook
+	breaker    *connector.CircuitBreaker
+	redactor   *connector.Redactor
+	timeouts   connector.Timeouts
 	db         *sql.DB
 }
 
+// Engine returns the engine of the connector, if it tells it.
+func (s *SQLProxy) Engine() DBEngine {
+	if engine, ok := s.connector.(interface{ Engine() string }); ok {
+		return DBEngine(engine.Engine())
+	}
+	return ""
+}
+
 func (s *SQLProxy) Open() (*sql.DB, error) {
+	if interceptable, ok := s.connector.(connector.Interceptable); ok {
+		hooks := s.hooks
+		if s.policy != nil {
+			hooks = append([]connector.Hook{s.policy}, hooks...)
+		}
+		if s.breaker != nil {
+			hooks = append([]connector.Hook{s.breaker}, hooks...)
+		}
+		interceptable.SetHooks(hooks...)
+		interceptable.SetRedactor(s.redactor)
+		interceptable.SetTimeouts(s.timeouts)
+	}
+
 	if db, err := s.connector.Open(s.logger, s.translator); err != nil {
 		return nil, s.translator.ErrorHandler(err)
 	} else {
@@ -35,7 +65,11 @@ func (s *SQLProxy) IsOpen() error {
 	defer cancel()
 
 	if stdErr := s.db.PingContext(ctx); stdErr != nil {
-		s.Close()
+		if errors.Is(stdErr, sqlerrors.CircuitOpen) {
+			// Fail fast, the breaker probes the DB by itself
+			return stdErr
+		}
+		s.closeDB()
 		if _, err := s.Open(); err != nil {
 			return s.translator.ErrorHandler(err)
 		} else {
@@ -47,17 +81,40 @@ func (s *SQLProxy) IsOpen() error {
 	return nil
 }
 
+// Close closes the hooks holding resources of their own (e.g. the audit hook, flushing its pending records)
+// before the DB they may write into.
 func (s *SQLProxy) Close() error {
 
 	if s.db == nil {
 		return sqlcommons.DBNotInitialized
 	}
 
+	for _, hook := range s.hooks {
+		switch closer := hook.(type) {
+		case io.Closer:
+			closer.Close()
+		case interface{ Close() }:
+			closer.Close()
+		}
+	}
+	return s.closeDB()
+}
+
+func (s *SQLProxy) closeDB() error {
 	err := s.db.Close()
 	s.db = nil
 	return err
 }
 
+// BeginReadOnly starts a transaction in which the engine itself rejects any write.
+func (s *SQLProxy) BeginReadOnly(ctx context.Context) (*sql.Tx, error) {
+	if err := s.IsOpen(); err != nil {
+		return nil, sqlcommons.ConnectionClosed
+	}
+
+	return s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+}
+
 func (s *SQLProxy) GetNextSequenceValue(ctx context.Context, sequenceName string) (int64, error) {
 	if err := s.IsOpen(); err != nil {
 		return 0, sqlcommons.ConnectionClosed
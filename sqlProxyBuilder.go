@@ -1,9 +1,12 @@
 package sqldb
 
 import (
+	"time"
+
 	"github.com/cdleo/go-commons/logger"
 	"github.com/cdleo/go-commons/sqlcommons"
 	"github.com/cdleo/go-sqldb/adapter"
+	"github.com/cdleo/go-sqldb/connector"
 )
 
 type SQLProxyBuilder struct {
@@ -31,6 +34,43 @@ func (s *SQLProxyBuilder) WithLogger(logger logger.Logger) *SQLProxyBuilder {
 	return s
 }
 
+func (s *SQLProxyBuilder) WithHooks(hooks ...connector.Hook) *SQLProxyBuilder {
+	s.proxy.hooks = append(s.proxy.hooks, hooks...)
+	return s
+}
+
+// WithPolicy sets the policy checked before any other hook, e.g. to make the proxy read-only.
+func (s *SQLProxyBuilder) WithPolicy(policy *connector.PolicyHook) *SQLProxyBuilder {
+	s.proxy.policy = policy
+	return s
+}
+
+// WithCircuitBreaker sets the breaker checked before any other hook, which also guards the connections and IsOpen.
+func (s *SQLProxyBuilder) WithCircuitBreaker(breaker *connector.CircuitBreaker) *SQLProxyBuilder {
+	s.proxy.breaker = breaker
+	return s
+}
+
+func (s *SQLProxyBuilder) WithRedactor(redactor *connector.Redactor) *SQLProxyBuilder {
+	s.proxy.redactor = redactor
+	return s
+}
+
+// WithDefaultQueryTimeout bounds every statement whose context has no deadline of its own.
+func (s *SQLProxyBuilder) WithDefaultQueryTimeout(timeout time.Duration) *SQLProxyBuilder {
+	s.proxy.timeouts.Default = timeout
+	return s
+}
+
+// WithTimeouts sets per-operation timeouts (query, exec, begin, commit), which take precedence over the default one.
+func (s *SQLProxyBuilder) WithTimeouts(timeouts connector.Timeouts) *SQLProxyBuilder {
+	if timeouts.Default == 0 {
+		timeouts.Default = s.proxy.timeouts.Default
+	}
+	s.proxy.timeouts = timeouts
+	return s
+}
+
 func (s *SQLProxyBuilder) Build() *SQLProxy {
 	return &s.proxy
 }
@@ -0,0 +1,140 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/cdleo/go-commons/sqlcommons"
+	"github.com/cdleo/go-sqldb/connector"
+)
+
+// InsertReturning executes an insert of a single row and returns the given columns of the inserted row, e.g. the
+// generated keys, by appending the clause of the engine to the statement: RETURNING ... INTO out binds on Oracle,
+// RETURNING on PostgreSQL and SQLite3 (falling back to last_insert_rowid() before SQLite 3.35, for a single column).
+// The integer values are returned as int64 on PostgreSQL and SQLite3, while the Oracle out binds return every value
+// as a string (see InsertReturningInto for typed values on every engine).
+func (s *SQLProxy) InsertReturning(ctx context.Context, query string, args []interface{}, returningColumns ...string) (map[string]interface{}, error) {
+
+	values := make([]interface{}, len(returningColumns))
+	dests := make([]interface{}, len(returningColumns))
+	for i := range values {
+		if s.Engine() == Oracle {
+			// The out binds take the type of their destination, which only a string holds losslessly
+			values[i] = new(string)
+			dests[i] = values[i]
+		} else {
+			dests[i] = &values[i]
+		}
+	}
+	if err := s.InsertReturningInto(ctx, query, args, returningColumns, dests...); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{}, len(returningColumns))
+	for i, column := range returningColumns {
+		if value, ok := values[i].(*string); ok {
+			keys[column] = *value
+		} else {
+			keys[column] = values[i]
+		}
+	}
+	return keys, nil
+}
+
+// InsertReturningInto executes an insert of a single row as InsertReturning does, storing the given columns of the
+// inserted row into the destinations (as given to Scan), whose types the Oracle out binds are bound by.
+func (s *SQLProxy) InsertReturningInto(ctx context.Context, query string, args []interface{}, returningColumns []string, dests ...interface{}) error {
+
+	if err := s.IsOpen(); err != nil {
+		return sqlcommons.ConnectionClosed
+	}
+	if len(returningColumns) == 0 {
+		return fmt.Errorf("no returned columns")
+	}
+	if len(dests) != len(returningColumns) {
+		return fmt.Errorf("%d destinations for %d returned columns", len(dests), len(returningColumns))
+	}
+
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	switch engine := s.Engine(); engine {
+	case Oracle:
+		return s.insertReturningInto(ctx, query, args, returningColumns, dests)
+	case PostgreSQL, SQLite3:
+		err := s.insertReturning(ctx, query, args, returningColumns, dests)
+		if err != nil && engine == SQLite3 && len(returningColumns) == 1 && isReturningUnsupported(err) {
+			return s.insertLastRowID(ctx, query, args, dests[0])
+		}
+		return err
+	default:
+		return sqlcommons.DBNotSupported
+	}
+}
+
+func (s *SQLProxy) insertReturning(ctx context.Context, query string, args []interface{}, columns []string, dests []interface{}) error {
+
+	rows, err := s.db.QueryContext(ctx, query+" RETURNING "+strings.Join(columns, ", "), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// SQLite3 reports the failures of the insert when the row is read, past the translation of the proxy
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return s.translator.ErrorHandler(err)
+		}
+		return sql.ErrNoRows
+	}
+	if err := rows.Scan(dests...); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// insertReturningInto binds the destinations as out arguments, which Oracle converts the returned columns to.
+func (s *SQLProxy) insertReturningInto(ctx context.Context, query string, args []interface{}, columns []string, dests []interface{}) error {
+
+	placeholders := make([]string, len(columns))
+	args = append([]interface{}(nil), args...)
+	for i, dest := range dests {
+		args = append(args, sql.Out{Dest: dest})
+		placeholders[i] = connector.Placeholder(string(Oracle), len(args))
+	}
+
+	query = fmt.Sprintf("%s RETURNING %s INTO %s", query, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (s *SQLProxy) insertLastRowID(ctx context.Context, query string, args []interface{}, dest interface{}) error {
+
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	switch d := dest.(type) {
+	case *int64:
+		*d = id
+	case *int:
+		*d = int(id)
+	case *interface{}:
+		*d = id
+	case sql.Scanner:
+		return d.Scan(id)
+	default:
+		return fmt.Errorf("unsupported destination %T for the last inserted rowid", dest)
+	}
+	return nil
+}
+
+// isReturningUnsupported tells whether SQLite rejected the RETURNING clause, as it does before 3.35.
+func isReturningUnsupported(err error) bool {
+	return strings.Contains(err.Error(), `near "RETURNING": syntax error`)
+}
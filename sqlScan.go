@@ -0,0 +1,218 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cdleo/go-sqldb/sqlerrors"
+)
+
+// Querier is implemented by *sql.DB, *sql.Tx and *sql.Conn.
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+type ScanMode string
+
+const (
+	// ScanLenient ignores the columns without a matching field, and the fields without a matching column.
+	ScanLenient ScanMode = "Lenient"
+	// ScanStrict requires every column to match a field, and every tagged field to match a column.
+	ScanStrict ScanMode = "Strict"
+)
+
+type scanModeCtxKey struct{}
+
+// WithScanMode returns a copy of ctx whose results are scanned with the given mode (lenient by default).
+func WithScanMode(ctx context.Context, mode ScanMode) context.Context {
+	return context.WithValue(ctx, scanModeCtxKey{}, mode)
+}
+
+func scanModeFromContext(ctx context.Context) ScanMode {
+	if mode, ok := ctx.Value(scanModeCtxKey{}).(ScanMode); ok {
+		return mode
+	}
+	return ScanLenient
+}
+
+// Get returns the first row of the query, scanned into T: either a scalar (single column) or a struct whose
+// fields are matched to the columns by their `db` tag (or their name), case-insensitively.
+// It returns sql.ErrNoRows when the query returns no rows.
+func Get[T any](ctx context.Context, q Querier, query string, args ...interface{}) (T, error) {
+
+	var item T
+	cursor, err := NewCursor[T](ctx, q, query, args...)
+	if err != nil {
+		return item, err
+	}
+	defer cursor.Close()
+
+	if !cursor.Next() {
+		if err := cursor.Err(); err != nil {
+			return item, err
+		}
+		return item, sql.ErrNoRows
+	}
+	return cursor.Value(), cursor.Close()
+}
+
+// Select returns every row of the query, scanned into T as Get does.
+func Select[T any](ctx context.Context, q Querier, query string, args ...interface{}) ([]T, error) {
+
+	cursor, err := NewCursor[T](ctx, q, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+
+	var items []T
+	for cursor.Next() {
+		items = append(items, cursor.Value())
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// newRowScanner returns the function scanning the current row of rows into a T.
+func newRowScanner[T any](ctx context.Context, rows *sql.Rows) (func(item *T) error, error) {
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	itemType := reflect.TypeOf((*T)(nil)).Elem()
+	if isScalar(itemType) {
+		if len(columns) != 1 {
+			return nil, fmt.Errorf("%w: %d columns returned, scanning into %s", sqlerrors.UnmappedColumn, len(columns), itemType)
+		}
+		return func(item *T) error {
+			return rows.Scan(item)
+		}, nil
+	}
+
+	fields := fieldsOf(itemType)
+	strict := scanModeFromContext(ctx) == ScanStrict
+	indexes := make([][]int, len(columns))
+	matched := make(map[string]bool)
+	for i, column := range columns {
+		name := strings.ToLower(column)
+		index, ok := fields.byName[name]
+		if !ok && strict {
+			return nil, fmt.Errorf("%w: column [%s] has no field in %s", sqlerrors.UnmappedColumn, column, itemType)
+		}
+		indexes[i] = index
+		matched[name] = true
+	}
+	if strict {
+		for _, name := range fields.tagged {
+			if !matched[name] {
+				return nil, fmt.Errorf("%w: field [%s] of %s has no column", sqlerrors.UnmappedColumn, name, itemType)
+			}
+		}
+	}
+
+	return func(item *T) error {
+		value := reflect.ValueOf(item).Elem()
+		dest := make([]interface{}, len(columns))
+		for i, index := range indexes {
+			if index == nil {
+				dest[i] = new(interface{})
+			} else {
+				dest[i] = fieldByIndex(value, index).Addr().Interface()
+			}
+		}
+		return rows.Scan(dest...)
+	}, nil
+}
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// isScalar tells whether the type is scanned from a single column, rather than field by field.
+func isScalar(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() != reflect.Struct || t == timeType || reflect.PointerTo(t).Implements(scannerType)
+}
+
+type structFields struct {
+	byName map[string][]int
+	names  []string
+	tagged []string
+}
+
+var fieldsCache sync.Map
+
+// fieldsOf maps the (lower-cased) column names to the index of the fields of a struct type, flattening the
+// embedded structs. The mapping is cached per type.
+func fieldsOf(t reflect.Type) *structFields {
+
+	if cached, ok := fieldsCache.Load(t); ok {
+		return cached.(*structFields)
+	}
+
+	fields := &structFields{byName: make(map[string][]int)}
+	collectFields(t, nil, fields)
+	cached, _ := fieldsCache.LoadOrStore(t, fields)
+	return cached.(*structFields)
+}
+
+func collectFields(t reflect.Type, parent []int, fields *structFields) {
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, hasTag := field.Tag.Lookup("db")
+		if tag == "-" {
+			continue
+		}
+		index := append(append([]int(nil), parent...), i)
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		embeddable := field.IsExported() || field.Type.Kind() != reflect.Pointer
+		if field.Anonymous && tag == "" && embeddable && fieldType.Kind() == reflect.Struct && !isScalar(fieldType) {
+			collectFields(fieldType, index, fields)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name := strings.ToLower(field.Name)
+		if hasTag && tag != "" {
+			name = strings.ToLower(tag)
+			fields.tagged = append(fields.tagged, name)
+		}
+		if _, exists := fields.byName[name]; !exists {
+			fields.byName[name] = index
+			fields.names = append(fields.names, name)
+		}
+	}
+}
+
+// fieldByIndex returns the nested field, allocating the nil embedded pointers on the way.
+func fieldByIndex(value reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				value.Set(reflect.New(value.Type().Elem()))
+			}
+			value = value.Elem()
+		}
+		value = value.Field(x)
+	}
+	return value
+}
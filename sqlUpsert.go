@@ -0,0 +1,255 @@
+package sqldb
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/cdleo/go-commons/sqlcommons"
+	"github.com/cdleo/go-sqldb/connector"
+)
+
+type UpsertResult struct {
+	RowsAffected int64
+	// Keys holds the returned columns, when the engine returns them (PostgreSQL and SQLite3, not Oracle's MERGE)
+	Keys map[string]interface{}
+}
+
+// UpsertBuilder inserts a row or, when a row with the same key columns exists, updates it (or leaves it
+// as is), with the statement of the engine: MERGE on Oracle, ON CONFLICT on PostgreSQL and SQLite3.
+type UpsertBuilder struct {
+	table     string
+	keys      []string
+	columns   []string
+	values    []interface{}
+	update    []string
+	doNothing bool
+	returning []string
+	err       error
+}
+
+// Upsert builds the upsert of a row given by its column values. By default, every non-key column is updated.
+func Upsert(table string, keyColumns []string, values map[string]interface{}) *UpsertBuilder {
+
+	b := &UpsertBuilder{table: table, keys: keyColumns}
+	for column := range values {
+		b.columns = append(b.columns, column)
+	}
+	sort.Strings(b.columns)
+	for _, column := range b.columns {
+		b.values = append(b.values, values[column])
+	}
+	return b
+}
+
+// UpsertStruct builds the upsert of a row given by a struct, whose fields are matched to the columns by their `db` tag (or their name).
+func UpsertStruct(table string, keyColumns []string, item interface{}) *UpsertBuilder {
+
+	b := &UpsertBuilder{table: table, keys: keyColumns}
+	value := reflect.Indirect(reflect.ValueOf(item))
+	if value.Kind() != reflect.Struct {
+		b.err = fmt.Errorf("%T is not a struct", item)
+		return b
+	}
+	fields := fieldsOf(value.Type())
+	for _, name := range fields.names {
+		if field, ok := fieldValue(value, fields.byName[name]); ok {
+			b.columns = append(b.columns, name)
+			b.values = append(b.values, field.Interface())
+		}
+	}
+	return b
+}
+
+// Omit leaves the columns out of the statement, e.g. the ones generated by the DB.
+func (b *UpsertBuilder) Omit(columns ...string) *UpsertBuilder {
+	for _, column := range columns {
+		for i := range b.columns {
+			if strings.EqualFold(b.columns[i], column) {
+				b.columns = append(b.columns[:i], b.columns[i+1:]...)
+				b.values = append(b.values[:i], b.values[i+1:]...)
+				break
+			}
+		}
+	}
+	return b
+}
+
+// Update sets the columns updated when the row exists.
+func (b *UpsertBuilder) Update(columns ...string) *UpsertBuilder {
+	b.update = columns
+	b.doNothing = false
+	return b
+}
+
+// DoNothing leaves the existing row as is.
+func (b *UpsertBuilder) DoNothing() *UpsertBuilder {
+	b.doNothing = true
+	return b
+}
+
+// Returning sets the columns returned as keys of the result, e.g. the generated ones.
+func (b *UpsertBuilder) Returning(columns ...string) *UpsertBuilder {
+	b.returning = columns
+	return b
+}
+
+// ToSQL returns the statement for the engine, and its bind arguments.
+func (b *UpsertBuilder) ToSQL(engine DBEngine) (string, []interface{}, error) {
+
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	if len(b.keys) == 0 || len(b.columns) == 0 {
+		return "", nil, fmt.Errorf("upsert into %s needs key columns and values", b.table)
+	}
+
+	update := b.updatedColumns()
+	switch engine {
+	case Oracle:
+		return b.merge(update), b.values, nil
+	case PostgreSQL, SQLite3:
+		return b.onConflict(string(engine), update), b.values, nil
+	default:
+		return "", nil, sqlcommons.DBNotSupported
+	}
+}
+
+func (b *UpsertBuilder) Exec(ctx context.Context, proxy *SQLProxy) (UpsertResult, error) {
+
+	if err := proxy.IsOpen(); err != nil {
+		return UpsertResult{}, sqlcommons.ConnectionClosed
+	}
+
+	query, args, err := b.ToSQL(proxy.Engine())
+	if err != nil {
+		return UpsertResult{}, err
+	}
+
+	if len(b.returning) == 0 || proxy.Engine() == Oracle {
+		result, err := proxy.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return UpsertResult{}, err
+		}
+		rows, err := result.RowsAffected()
+		return UpsertResult{RowsAffected: rows}, err
+	}
+
+	rows, err := proxy.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return UpsertResult{}, err
+	}
+	defer rows.Close()
+
+	var result UpsertResult
+	for rows.Next() {
+		keys := make([]interface{}, len(b.returning))
+		dest := make([]interface{}, len(b.returning))
+		for i := range keys {
+			dest[i] = &keys[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return UpsertResult{}, err
+		}
+		result.RowsAffected++
+		result.Keys = make(map[string]interface{})
+		for i, column := range b.returning {
+			result.Keys[column] = keys[i]
+		}
+	}
+	return result, rows.Err()
+}
+
+func (b *UpsertBuilder) updatedColumns() []string {
+
+	if b.doNothing {
+		return nil
+	}
+	if b.update != nil {
+		return b.update
+	}
+	var update []string
+	for _, column := range b.columns {
+		if !b.isKey(column) {
+			update = append(update, column)
+		}
+	}
+	return update
+}
+
+func (b *UpsertBuilder) isKey(column string) bool {
+	for _, key := range b.keys {
+		if strings.EqualFold(key, column) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *UpsertBuilder) onConflict(engine string, update []string) string {
+
+	placeholders := make([]string, len(b.columns))
+	for i := range placeholders {
+		placeholders[i] = connector.Placeholder(engine, i+1)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s)", b.table, strings.Join(b.columns, ", "),
+		strings.Join(placeholders, ", "), strings.Join(b.keys, ", "))
+	if len(update) == 0 {
+		sb.WriteString(" DO NOTHING")
+	} else {
+		assignments := make([]string, len(update))
+		for i, column := range update {
+			assignments[i] = fmt.Sprintf("%s = EXCLUDED.%s", column, column)
+		}
+		sb.WriteString(" DO UPDATE SET " + strings.Join(assignments, ", "))
+	}
+	if len(b.returning) > 0 {
+		sb.WriteString(" RETURNING " + strings.Join(b.returning, ", "))
+	}
+	return sb.String()
+}
+
+func (b *UpsertBuilder) merge(update []string) string {
+
+	selected := make([]string, len(b.columns))
+	inserted := make([]string, len(b.columns))
+	for i, column := range b.columns {
+		selected[i] = fmt.Sprintf("%s AS %s", connector.Placeholder(string(Oracle), i+1), column)
+		inserted[i] = "s." + column
+	}
+	conditions := make([]string, len(b.keys))
+	for i, key := range b.keys {
+		conditions[i] = fmt.Sprintf("t.%s = s.%s", key, key)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "MERGE INTO %s t USING (SELECT %s FROM DUAL) s ON (%s)", b.table, strings.Join(selected, ", "),
+		strings.Join(conditions, " AND "))
+	if len(update) > 0 {
+		assignments := make([]string, len(update))
+		for i, column := range update {
+			assignments[i] = fmt.Sprintf("t.%s = s.%s", column, column)
+		}
+		sb.WriteString(" WHEN MATCHED THEN UPDATE SET " + strings.Join(assignments, ", "))
+	}
+	fmt.Fprintf(&sb, " WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)", strings.Join(b.columns, ", "), strings.Join(inserted, ", "))
+	return sb.String()
+}
+
+// fieldValue returns the nested field, or false if it lies within a nil embedded pointer.
+func fieldValue(value reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				return reflect.Value{}, false
+			}
+			value = value.Elem()
+		}
+		value = value.Field(x)
+	}
+	return value, true
+}
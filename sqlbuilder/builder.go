@@ -0,0 +1,322 @@
+/*
+Package sqlbuilder builds statements structurally and renders them in the native syntax of each engine (Oracle,
+PostgreSQL or SQLite3): bind placeholders, identifier quoting, row limiting and literals. The rendered statements
+are executed with connector.WithNativeSQL, so the adapter doesn't translate them again.
+*/
+package sqlbuilder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cdleo/go-commons/sqlcommons"
+	"github.com/cdleo/go-sqldb/connector"
+)
+
+const (
+	oracleEngine   = "Oracle"
+	postgresEngine = "PostgreSQL"
+	sqlite3Engine  = "SQLite3"
+)
+
+// Statement is implemented by every builder of the package.
+type Statement interface {
+	ToSQL(engine string) (string, []interface{}, error)
+}
+
+// Querier is implemented by *sql.DB, *sql.Tx and *sql.Conn.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// returner is implemented by the statements with a RETURNING clause.
+type returner interface {
+	destinations() []interface{}
+}
+
+// Query renders the statement for the engine and runs it, bypassing the translation.
+func Query(ctx context.Context, db Querier, engine string, stmt Statement) (*sql.Rows, error) {
+
+	query, args, err := stmt.ToSQL(engine)
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(connector.WithNativeSQL(ctx), query, args...)
+}
+
+// Exec renders the statement for the engine and executes it, bypassing the translation. The columns returned
+// by a statement with a RETURNING clause are stored into the destinations given by Into: through out binds on
+// Oracle, by scanning the returned row elsewhere.
+func Exec(ctx context.Context, db Querier, engine string, stmt Statement) (sql.Result, error) {
+
+	query, args, err := stmt.ToSQL(engine)
+	if err != nil {
+		return nil, err
+	}
+	ctx = connector.WithNativeSQL(ctx)
+
+	r, ok := stmt.(returner)
+	if !ok || len(r.destinations()) == 0 || engine == oracleEngine {
+		return db.ExecContext(ctx, query, args...)
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var affected returnedRows
+	for rows.Next() {
+		if err := rows.Scan(r.destinations()...); err != nil {
+			return nil, err
+		}
+		affected++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return affected, rows.Close()
+}
+
+// returnedRows is the result of a statement whose rows have been returned.
+type returnedRows int64
+
+func (r returnedRows) LastInsertId() (int64, error) {
+	return 0, sqlcommons.OpNotSupported
+}
+
+func (r returnedRows) RowsAffected() (int64, error) {
+	return int64(r), nil
+}
+
+type literal struct {
+	value  interface{}
+	isDate bool
+}
+
+// Lit renders a value inline, rather than as a bind argument: NULL, a boolean, a number, a string or a time
+// (as a timestamp). Booleans are rendered as 1 and 0 on the engines lacking them in SQL (Oracle and SQLite3).
+func Lit(value interface{}) interface{} {
+	return literal{value: value}
+}
+
+// LitDate renders the date of t inline.
+func LitDate(t time.Time) interface{} {
+	return literal{value: t, isDate: true}
+}
+
+// renderer accumulates the text and the bind arguments of a statement for an engine.
+type renderer struct {
+	engine string
+	sb     strings.Builder
+	args   []interface{}
+	err    error
+}
+
+func newRenderer(engine string) (*renderer, error) {
+	switch engine {
+	case oracleEngine, postgresEngine, sqlite3Engine:
+		return &renderer{engine: engine}, nil
+	default:
+		return nil, sqlcommons.DBNotSupported
+	}
+}
+
+func (r *renderer) write(parts ...string) {
+	for _, part := range parts {
+		r.sb.WriteString(part)
+	}
+}
+
+func (r *renderer) result() (string, []interface{}, error) {
+	if r.err != nil {
+		return "", nil, r.err
+	}
+	return r.sb.String(), r.args, nil
+}
+
+// bind returns the placeholder of the value, or the value itself when it is a literal.
+func (r *renderer) bind(value interface{}) string {
+
+	if lit, ok := value.(literal); ok {
+		return r.literal(lit)
+	}
+	if b, ok := value.(bool); ok && r.engine == oracleEngine {
+		// Oracle has no boolean type in SQL, they are stored as numbers
+		value = 0
+		if b {
+			value = 1
+		}
+	}
+	r.args = append(r.args, value)
+	return connector.Placeholder(r.engine, len(r.args))
+}
+
+// expr renders an expression, binding the arguments to its "?" placeholders (outside the quoted literals).
+func (r *renderer) expr(expr string, args []interface{}) string {
+
+	var sb strings.Builder
+	n := 0
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(expr[i+1:], c)
+			if end < 0 {
+				r.fail(fmt.Errorf("expression [%s] has an unterminated literal", expr))
+				return expr
+			}
+			sb.WriteString(expr[i : i+end+2])
+			i += end + 1
+		case c == '?':
+			if n >= len(args) {
+				r.fail(fmt.Errorf("expression [%s] has more placeholders than arguments", expr))
+				return expr
+			}
+			sb.WriteString(r.bind(args[n]))
+			n++
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	if n != len(args) {
+		r.fail(fmt.Errorf("expression [%s] has %d placeholders for %d arguments", expr, n, len(args)))
+	}
+	return sb.String()
+}
+
+func (r *renderer) literal(lit literal) string {
+
+	switch v := lit.value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if r.engine == postgresEngine {
+			return strings.ToUpper(fmt.Sprint(v))
+		}
+		if v {
+			return "1"
+		}
+		return "0"
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	case time.Time:
+		prefix := "TIMESTAMP "
+		text := v.Format("2006-01-02 15:04:05.999999999")
+		if lit.isDate {
+			prefix, text = "DATE ", v.Format("2006-01-02")
+		}
+		if r.engine == sqlite3Engine {
+			prefix = ""
+		}
+		return prefix + "'" + text + "'"
+	default:
+		r.fail(fmt.Errorf("unsupported literal %T", v))
+		return ""
+	}
+}
+
+func (r *renderer) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+var (
+	identRegExp     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$#]*$`)
+	identPathRegExp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$#]*(\.[A-Za-z_][A-Za-z0-9_$#]*)*(\.\*)?$`)
+)
+
+// reservedWords are the words commonly used as names that are reserved by any of the engines.
+var reservedWords = map[string]bool{
+	"ACCESS": true, "ALL": true, "AND": true, "ANY": true, "AS": true, "ASC": true, "BETWEEN": true, "BY": true,
+	"CASE": true, "CHECK": true, "COLUMN": true, "COMMENT": true, "CREATE": true, "CURRENT": true, "DATE": true,
+	"DEFAULT": true, "DELETE": true, "DESC": true, "DISTINCT": true, "DROP": true, "ELSE": true, "END": true,
+	"EXISTS": true, "FILE": true, "FOR": true, "FROM": true, "GRANT": true, "GROUP": true, "HAVING": true, "IN": true,
+	"INDEX": true, "INSERT": true, "INTO": true, "IS": true, "JOIN": true, "KEY": true, "LEVEL": true, "LIKE": true,
+	"LIMIT": true, "MODE": true, "NOT": true, "NULL": true, "NUMBER": true, "OFFSET": true, "ON": true, "OPTION": true,
+	"OR": true, "ORDER": true, "RESOURCE": true, "ROW": true, "ROWID": true, "ROWNUM": true, "ROWS": true,
+	"SELECT": true, "SESSION": true, "SET": true, "SIZE": true, "START": true, "TABLE": true, "THEN": true,
+	"TIMESTAMP": true, "TO": true, "UID": true, "UNION": true, "UNIQUE": true, "UPDATE": true, "USER": true,
+	"VALUES": true, "VIEW": true, "WHEN": true, "WHERE": true, "WITH": true,
+}
+
+// name renders a (possibly qualified) identifier, leaving anything else (e.g. an expression) verbatim.
+func (r *renderer) name(name string) string {
+
+	name = strings.TrimSpace(name)
+	if !identPathRegExp.MatchString(name) {
+		return name
+	}
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if part != "*" {
+			parts[i] = r.ident(part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// ident quotes the reserved words. They are quoted in the case the engine folds the unquoted names to, so
+// they keep naming the same object.
+func (r *renderer) ident(ident string) string {
+
+	if identRegExp.MatchString(ident) && !reservedWords[strings.ToUpper(ident)] {
+		return ident
+	}
+	switch r.engine {
+	case oracleEngine:
+		ident = strings.ToUpper(ident)
+	case postgresEngine:
+		ident = strings.ToLower(ident)
+	}
+	return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"`
+}
+
+// table renders a table name followed by an optional alias, as in "customers c".
+func (r *renderer) table(table string) string {
+	fields := strings.Fields(table)
+	if len(fields) == 2 {
+		return r.name(fields[0]) + " " + r.ident(fields[1])
+	}
+	return r.name(table)
+}
+
+// names renders a comma separated list of names.
+func (r *renderer) names(names []string) string {
+	rendered := make([]string, len(names))
+	for i, name := range names {
+		rendered[i] = r.name(name)
+	}
+	return strings.Join(rendered, ", ")
+}
+
+// returning renders a RETURNING clause: with out binds into the destinations on Oracle, which needs them.
+func (r *renderer) returning(columns []string, dests []interface{}) {
+
+	if len(columns) == 0 {
+		return
+	}
+	r.write(" RETURNING ", r.names(columns))
+	if r.engine != oracleEngine {
+		return
+	}
+	if len(dests) != len(columns) {
+		r.fail(fmt.Errorf("%d destinations for %d returned columns", len(dests), len(columns)))
+		return
+	}
+	placeholders := make([]string, len(dests))
+	for i, dest := range dests {
+		r.args = append(r.args, sql.Out{Dest: dest})
+		placeholders[i] = connector.Placeholder(r.engine, len(r.args))
+	}
+	r.write(" INTO ", strings.Join(placeholders, ", "))
+}
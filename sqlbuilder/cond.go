@@ -0,0 +1,167 @@
+package sqlbuilder
+
+import (
+	"strings"
+)
+
+// Cond is a condition of a WHERE clause or of a join.
+type Cond interface {
+	render(r *renderer) string
+}
+
+type exprCond struct {
+	expr string
+	args []interface{}
+}
+
+type compareCond struct {
+	column   string
+	operator string
+	value    interface{}
+}
+
+type inCond struct {
+	column string
+	values []interface{}
+}
+
+type nullCond struct {
+	column string
+	not    bool
+}
+
+type logicCond struct {
+	operator string
+	conds    []Cond
+}
+
+type notCond struct {
+	cond Cond
+}
+
+// Expr is a raw condition whose arguments are bound to its "?" placeholders, e.g. Expr("age BETWEEN ? AND ?", 18, 65).
+func Expr(expr string, args ...interface{}) Cond {
+	return exprCond{expr, args}
+}
+
+// Eq compares the column with the value, rendering "IS NULL" for a nil value.
+func Eq(column string, value interface{}) Cond {
+	if value == nil {
+		return IsNull(column)
+	}
+	return compareCond{column, "=", value}
+}
+
+// Ne compares the column with the value, rendering "IS NOT NULL" for a nil value.
+func Ne(column string, value interface{}) Cond {
+	if value == nil {
+		return IsNotNull(column)
+	}
+	return compareCond{column, "<>", value}
+}
+
+func Lt(column string, value interface{}) Cond {
+	return compareCond{column, "<", value}
+}
+
+func Le(column string, value interface{}) Cond {
+	return compareCond{column, "<=", value}
+}
+
+func Gt(column string, value interface{}) Cond {
+	return compareCond{column, ">", value}
+}
+
+func Ge(column string, value interface{}) Cond {
+	return compareCond{column, ">=", value}
+}
+
+func Like(column string, pattern interface{}) Cond {
+	return compareCond{column, "LIKE", pattern}
+}
+
+// In tells whether the column holds one of the values. With no values, it is always false.
+func In(column string, values ...interface{}) Cond {
+	return inCond{column, values}
+}
+
+func IsNull(column string) Cond {
+	return nullCond{column, false}
+}
+
+func IsNotNull(column string) Cond {
+	return nullCond{column, true}
+}
+
+func And(conds ...Cond) Cond {
+	return logicCond{"AND", conds}
+}
+
+func Or(conds ...Cond) Cond {
+	return logicCond{"OR", conds}
+}
+
+func Not(cond Cond) Cond {
+	return notCond{cond}
+}
+
+func (c exprCond) render(r *renderer) string {
+	return r.expr(c.expr, c.args)
+}
+
+func (c compareCond) render(r *renderer) string {
+	return r.name(c.column) + " " + c.operator + " " + r.bind(c.value)
+}
+
+func (c inCond) render(r *renderer) string {
+
+	if len(c.values) == 0 {
+		return "1 = 0"
+	}
+	placeholders := make([]string, len(c.values))
+	for i, value := range c.values {
+		placeholders[i] = r.bind(value)
+	}
+	return r.name(c.column) + " IN (" + strings.Join(placeholders, ", ") + ")"
+}
+
+func (c nullCond) render(r *renderer) string {
+	if c.not {
+		return r.name(c.column) + " IS NOT NULL"
+	}
+	return r.name(c.column) + " IS NULL"
+}
+
+func (c logicCond) render(r *renderer) string {
+
+	if len(c.conds) == 0 {
+		if c.operator == "AND" {
+			return "1 = 1"
+		}
+		return "1 = 0"
+	}
+	rendered := make([]string, len(c.conds))
+	for i, cond := range c.conds {
+		rendered[i] = cond.render(r)
+	}
+	if len(rendered) == 1 {
+		return rendered[0]
+	}
+	return "(" + strings.Join(rendered, " "+c.operator+" ") + ")"
+}
+
+func (c notCond) render(r *renderer) string {
+	return "NOT (" + c.cond.render(r) + ")"
+}
+
+// where renders the WHERE clause of the conditions, all of them required.
+func where(r *renderer, conds []Cond) {
+	if len(conds) == 0 {
+		return
+	}
+	rendered := make([]string, len(conds))
+	for i, cond := range conds {
+		rendered[i] = cond.render(r)
+	}
+	r.write(" WHERE ", strings.Join(rendered, " AND "))
+}
@@ -0,0 +1,183 @@
+package sqlbuilder
+
+import (
+	"fmt"
+	"strings"
+)
+
+// returning holds the RETURNING clause shared by the INSERT, UPDATE and DELETE builders.
+type returning struct {
+	columns []string
+	dests   []interface{}
+}
+
+func (r *returning) destinations() []interface{} {
+	return r.dests
+}
+
+type InsertBuilder struct {
+	returning
+	table   string
+	columns []string
+	rows    [][]interface{}
+}
+
+// Insert builds the insertion of rows into the table.
+func Insert(table string) *InsertBuilder {
+	return &InsertBuilder{table: table}
+}
+
+func (b *InsertBuilder) Columns(columns ...string) *InsertBuilder {
+	b.columns = columns
+	return b
+}
+
+// Values adds a row, with a value per column. Oracle takes a single row per statement.
+func (b *InsertBuilder) Values(values ...interface{}) *InsertBuilder {
+	b.rows = append(b.rows, values)
+	return b
+}
+
+// Returning sets the columns returned by the statement, e.g. the generated ones.
+func (b *InsertBuilder) Returning(columns ...string) *InsertBuilder {
+	b.returning.columns = columns
+	return b
+}
+
+// Into sets the destinations of the returned columns of the (single) row, as given to Scan. Oracle needs them
+// to render the statement, binding them as out arguments.
+func (b *InsertBuilder) Into(dests ...interface{}) *InsertBuilder {
+	b.returning.dests = dests
+	return b
+}
+
+func (b *InsertBuilder) ToSQL(engine string) (string, []interface{}, error) {
+
+	r, err := newRenderer(engine)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(b.columns) == 0 || len(b.rows) == 0 {
+		return "", nil, fmt.Errorf("insert into %s needs columns and values", b.table)
+	}
+	if len(b.rows) > 1 && engine == oracleEngine {
+		return "", nil, fmt.Errorf("insert into %s: Oracle takes a single row per statement", b.table)
+	}
+
+	values := make([]string, len(b.rows))
+	for i, row := range b.rows {
+		if len(row) != len(b.columns) {
+			return "", nil, fmt.Errorf("%d values for %d columns", len(row), len(b.columns))
+		}
+		placeholders := make([]string, len(row))
+		for j, value := range row {
+			placeholders[j] = r.bind(value)
+		}
+		values[i] = "(" + strings.Join(placeholders, ", ") + ")"
+	}
+	r.write("INSERT INTO ", r.table(b.table), " (", r.names(b.columns), ") VALUES ", strings.Join(values, ", "))
+	r.returning(b.returning.columns, b.returning.dests)
+	return r.result()
+}
+
+type assignment struct {
+	column string
+	value  interface{}
+}
+
+type UpdateBuilder struct {
+	returning
+	table string
+	set   []assignment
+	where []Cond
+}
+
+// Update builds the update of the rows of the table.
+func Update(table string) *UpdateBuilder {
+	return &UpdateBuilder{table: table}
+}
+
+func (b *UpdateBuilder) Set(column string, value interface{}) *UpdateBuilder {
+	b.set = append(b.set, assignment{column, value})
+	return b
+}
+
+// Where adds conditions, all of them required.
+func (b *UpdateBuilder) Where(conds ...Cond) *UpdateBuilder {
+	b.where = append(b.where, conds...)
+	return b
+}
+
+// Returning sets the columns returned by the statement.
+func (b *UpdateBuilder) Returning(columns ...string) *UpdateBuilder {
+	b.returning.columns = columns
+	return b
+}
+
+// Into sets the destinations of the returned columns of the (single) row, as InsertBuilder.Into does.
+func (b *UpdateBuilder) Into(dests ...interface{}) *UpdateBuilder {
+	b.returning.dests = dests
+	return b
+}
+
+func (b *UpdateBuilder) ToSQL(engine string) (string, []interface{}, error) {
+
+	r, err := newRenderer(engine)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(b.set) == 0 {
+		return "", nil, fmt.Errorf("update of %s needs values", b.table)
+	}
+
+	assignments := make([]string, len(b.set))
+	for i, set := range b.set {
+		assignments[i] = r.name(set.column) + " = " + r.bind(set.value)
+	}
+	r.write("UPDATE ", r.table(b.table), " SET ", strings.Join(assignments, ", "))
+	where(r, b.where)
+	r.returning(b.returning.columns, b.returning.dests)
+	return r.result()
+}
+
+type DeleteBuilder struct {
+	returning
+	table string
+	where []Cond
+}
+
+// Delete builds the deletion of the rows of the table.
+func Delete(table string) *DeleteBuilder {
+	return &DeleteBuilder{table: table}
+}
+
+// Where adds conditions, all of them required.
+func (b *DeleteBuilder) Where(conds ...Cond) *DeleteBuilder {
+	b.where = append(b.where, conds...)
+	return b
+}
+
+// Returning sets the columns returned by the statement.
+func (b *DeleteBuilder) Returning(columns ...string) *DeleteBuilder {
+	b.returning.columns = columns
+	return b
+}
+
+// Into sets the destinations of the returned columns of the (single) row, as InsertBuilder.Into does.
+func (b *DeleteBuilder) Into(dests ...interface{}) *DeleteBuilder {
+	b.returning.dests = dests
+	return b
+}
+
+func (b *DeleteBuilder) ToSQL(engine string) (string, []interface{}, error) {
+
+	r, err := newRenderer(engine)
+	if err != nil {
+		return "", nil, err
+	}
+
+	r.write("DELETE FROM ", r.table(b.table))
+	where(r, b.where)
+	r.returning(b.returning.columns, b.returning.dests)
+	return r.result()
+}
@@ -0,0 +1,154 @@
+package sqlbuilder
+
+import (
+	"fmt"
+	"strings"
+)
+
+type join struct {
+	kind  string
+	table string
+	on    Cond
+}
+
+type SelectBuilder struct {
+	columns  []string
+	distinct bool
+	from     string
+	joins    []join
+	where    []Cond
+	groupBy  []string
+	having   []Cond
+	orderBy  []string
+	limit    int
+	offset   int
+}
+
+// Select builds a query of the given columns (all of them, if none).
+func Select(columns ...string) *SelectBuilder {
+	return &SelectBuilder{columns: columns}
+}
+
+func (b *SelectBuilder) Distinct() *SelectBuilder {
+	b.distinct = true
+	return b
+}
+
+// From sets the table, optionally followed by an alias (e.g. "customers c").
+func (b *SelectBuilder) From(table string) *SelectBuilder {
+	b.from = table
+	return b
+}
+
+func (b *SelectBuilder) Join(table string, on Cond) *SelectBuilder {
+	b.joins = append(b.joins, join{"JOIN", table, on})
+	return b
+}
+
+func (b *SelectBuilder) LeftJoin(table string, on Cond) *SelectBuilder {
+	b.joins = append(b.joins, join{"LEFT JOIN", table, on})
+	return b
+}
+
+// Where adds conditions, all of them required.
+func (b *SelectBuilder) Where(conds ...Cond) *SelectBuilder {
+	b.where = append(b.where, conds...)
+	return b
+}
+
+func (b *SelectBuilder) GroupBy(columns ...string) *SelectBuilder {
+	b.groupBy = append(b.groupBy, columns...)
+	return b
+}
+
+func (b *SelectBuilder) Having(conds ...Cond) *SelectBuilder {
+	b.having = append(b.having, conds...)
+	return b
+}
+
+// OrderBy adds ordering columns, optionally followed by their direction (e.g. "name DESC").
+func (b *SelectBuilder) OrderBy(columns ...string) *SelectBuilder {
+	b.orderBy = append(b.orderBy, columns...)
+	return b
+}
+
+func (b *SelectBuilder) Limit(limit int) *SelectBuilder {
+	b.limit = limit
+	return b
+}
+
+func (b *SelectBuilder) Offset(offset int) *SelectBuilder {
+	b.offset = offset
+	return b
+}
+
+// ToSQL returns the query for the engine, and its bind arguments. The rows are limited by OFFSET ... FETCH NEXT
+// on Oracle (12c onwards), by LIMIT ... OFFSET elsewhere.
+func (b *SelectBuilder) ToSQL(engine string) (string, []interface{}, error) {
+
+	r, err := newRenderer(engine)
+	if err != nil {
+		return "", nil, err
+	}
+	if b.from == "" {
+		return "", nil, fmt.Errorf("select needs a table")
+	}
+
+	r.write("SELECT ")
+	if b.distinct {
+		r.write("DISTINCT ")
+	}
+	if len(b.columns) == 0 {
+		r.write("*")
+	} else {
+		r.write(r.names(b.columns))
+	}
+	r.write(" FROM ", r.table(b.from))
+	for _, join := range b.joins {
+		r.write(" ", join.kind, " ", r.table(join.table), " ON ", join.on.render(r))
+	}
+	where(r, b.where)
+	if len(b.groupBy) > 0 {
+		r.write(" GROUP BY ", r.names(b.groupBy))
+	}
+	if len(b.having) > 0 {
+		r.write(" HAVING ", And(b.having...).render(r))
+	}
+	if len(b.orderBy) > 0 {
+		order := make([]string, len(b.orderBy))
+		for i, column := range b.orderBy {
+			fields := strings.Fields(column)
+			if len(fields) == 2 && (strings.EqualFold(fields[1], "ASC") || strings.EqualFold(fields[1], "DESC")) {
+				order[i] = r.name(fields[0]) + " " + strings.ToUpper(fields[1])
+			} else {
+				order[i] = r.name(column)
+			}
+		}
+		r.write(" ORDER BY ", strings.Join(order, ", "))
+	}
+	b.limitRows(r)
+	return r.result()
+}
+
+func (b *SelectBuilder) limitRows(r *renderer) {
+
+	if r.engine == oracleEngine {
+		if b.offset > 0 {
+			r.write(fmt.Sprintf(" OFFSET %d ROWS", b.offset))
+		}
+		if b.limit > 0 {
+			r.write(fmt.Sprintf(" FETCH NEXT %d ROWS ONLY", b.limit))
+		}
+		return
+	}
+
+	if b.limit > 0 {
+		r.write(fmt.Sprintf(" LIMIT %d", b.limit))
+	} else if b.offset > 0 && r.engine == sqlite3Engine {
+		// SQLite3 takes no OFFSET without a LIMIT
+		r.write(" LIMIT -1")
+	}
+	if b.offset > 0 {
+		r.write(fmt.Sprintf(" OFFSET %d", b.offset))
+	}
+}
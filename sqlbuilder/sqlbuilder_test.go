@@ -0,0 +1,138 @@
+package sqlbuilder
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/cdleo/go-commons/logger"
+	"github.com/cdleo/go-commons/sqlcommons"
+	"github.com/cdleo/go-sqldb/adapter"
+	"github.com/cdleo/go-sqldb/connector"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Select_RendersPerEngine(t *testing.T) {
+	query := Select("c.id", "c.name", "g.groupname").
+		From("customers c").
+		Join("customers_groups g", Expr("g.id = c.cust_group")).
+		Where(Eq("c.active", true), Or(Gt("c.age", 18), IsNull("c.age")), In("c.level", 1, 2)).
+		OrderBy("c.name DESC").
+		Limit(10).
+		Offset(20)
+
+	statement, args, err := query.ToSQL(oracleEngine)
+	require.NoError(t, err)
+	require.Equal(t, `SELECT c.id, c.name, g.groupname FROM customers c JOIN customers_groups g ON g.id = c.cust_group `+
+		`WHERE c.active = :1 AND (c.age > :2 OR c.age IS NULL) AND c."LEVEL" IN (:3, :4) `+
+		`ORDER BY c.name DESC OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY`, statement)
+	require.Equal(t, []interface{}{1, 18, 1, 2}, args)
+
+	statement, args, err = query.ToSQL(postgresEngine)
+	require.NoError(t, err)
+	require.Equal(t, `SELECT c.id, c.name, g.groupname FROM customers c JOIN customers_groups g ON g.id = c.cust_group `+
+		`WHERE c.active = $1 AND (c.age > $2 OR c.age IS NULL) AND c."level" IN ($3, $4) `+
+		`ORDER BY c.name DESC LIMIT 10 OFFSET 20`, statement)
+	require.Equal(t, []interface{}{true, 18, 1, 2}, args)
+
+	statement, _, err = Select().From("user").Offset(5).ToSQL(sqlite3Engine)
+	require.NoError(t, err)
+	require.Equal(t, `SELECT * FROM "user" LIMIT -1 OFFSET 5`, statement)
+
+	_, _, err = query.ToSQL("MockDB")
+	require.ErrorIs(t, err, sqlcommons.DBNotSupported)
+	_, _, err = Select().From("customers").Where(Expr("name = 'x AND age > ?", 18)).ToSQL(sqlite3Engine)
+	require.ErrorContains(t, err, "unterminated literal")
+}
+
+func Test_Literals_RenderPerEngine(t *testing.T) {
+	day := time.Date(2024, 2, 29, 13, 45, 0, 0, time.UTC)
+	query := Update("customers").
+		Set("active", Lit(true)).
+		Set("updatetime", Lit(day)).
+		Set("name", Lit("O'Brien")).
+		Where(Lt("birth", LitDate(day)))
+
+	statement, args, err := query.ToSQL(oracleEngine)
+	require.NoError(t, err)
+	require.Equal(t, `UPDATE customers SET active = 1, updatetime = TIMESTAMP '2024-02-29 13:45:00', name = 'O''Brien' `+
+		`WHERE birth < DATE '2024-02-29'`, statement)
+	require.Empty(t, args)
+
+	statement, _, err = query.ToSQL(postgresEngine)
+	require.NoError(t, err)
+	require.Equal(t, `UPDATE customers SET active = TRUE, updatetime = TIMESTAMP '2024-02-29 13:45:00', name = 'O''Brien' `+
+		`WHERE birth < DATE '2024-02-29'`, statement)
+
+	statement, _, err = query.ToSQL(sqlite3Engine)
+	require.NoError(t, err)
+	require.Equal(t, `UPDATE customers SET active = 1, updatetime = '2024-02-29 13:45:00', name = 'O''Brien' `+
+		`WHERE birth < '2024-02-29'`, statement)
+}
+
+func Test_Returning_UsesOutBindsOnOracle(t *testing.T) {
+	var id int64
+	query := Insert("customers").Columns("name", "cust_group").Values("Juan", 1).Returning("id").Into(&id)
+
+	statement, args, err := query.ToSQL(oracleEngine)
+	require.NoError(t, err)
+	require.Equal(t, "INSERT INTO customers (name, cust_group) VALUES (:1, :2) RETURNING id INTO :3", statement)
+	require.Len(t, args, 3)
+
+	statement, args, err = query.ToSQL(postgresEngine)
+	require.NoError(t, err)
+	require.Equal(t, "INSERT INTO customers (name, cust_group) VALUES ($1, $2) RETURNING id", statement)
+	require.Len(t, args, 2)
+
+	_, _, err = Delete("customers").Returning("id").ToSQL(oracleEngine)
+	require.Error(t, err)
+}
+
+// garblingAdapter breaks any statement it translates.
+type garblingAdapter struct {
+	sqlcommons.SQLAdapter
+}
+
+func (a garblingAdapter) Translate(query string) string {
+	return "garbled " + query
+}
+
+func Test_Exec_BypassesTranslation(t *testing.T) {
+	// Setup
+	db, err := connector.NewSqlite3Connector(":memory:").Open(logger.NewNoLogLogger(), garblingAdapter{adapter.NewSQLite3Adapter()})
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	_, err = db.ExecContext(connector.WithNativeSQL(ctx), "CREATE TABLE customers (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, \"order\" INT)")
+	require.NoError(t, err)
+
+	// Exec
+	var id int64
+	result, err := Exec(ctx, db, sqlite3Engine, Insert("customers").Columns("name", "order").Values("Juan", 1).Returning("id").Into(&id))
+	require.NoError(t, err)
+	rows, err := result.RowsAffected()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+	require.Equal(t, int64(1), id)
+
+	_, err = Exec(ctx, db, sqlite3Engine, Insert("customers").Columns("name", "order").Values("Ana", 2).Values("Luis", 3))
+	require.NoError(t, err)
+
+	cursor, err := Query(ctx, db, sqlite3Engine, Select("name").From("customers").Where(Ge("order", 2)).OrderBy("order DESC"))
+	require.NoError(t, err)
+	defer cursor.Close()
+	var names []string
+	for cursor.Next() {
+		var name string
+		require.NoError(t, cursor.Scan(&name))
+		names = append(names, name)
+	}
+	require.Equal(t, []string{"Luis", "Ana"}, names)
+
+	_, err = db.ExecContext(ctx, "DELETE FROM customers")
+	require.Error(t, err)
+	_, err = Exec(ctx, db, sqlite3Engine, Delete("customers").Where(Eq("name", sql.NullString{})))
+	require.NoError(t, err)
+}
@@ -0,0 +1,316 @@
+/*
+Package sqldbtest helps testing sqldb itself, the connectors and adapters built for it, and the code using it.
+*/
+package sqldbtest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cdleo/go-commons/logger"
+	"github.com/cdleo/go-commons/sqlcommons"
+	sqldb "github.com/cdleo/go-sqldb"
+	"github.com/stretchr/testify/require"
+)
+
+// dialect holds what sets the engines apart in the conformance suite.
+type dialect struct {
+	integer   string
+	varchar   string
+	sequences bool
+	// unraised are the portable errors the engine never raises (e.g. SQLite3 doesn't enforce the column sizes)
+	unraised []error
+}
+
+var dialects = map[sqldb.DBEngine]dialect{
+	sqldb.Oracle:     {integer: "NUMBER(10)", varchar: "VARCHAR2", sequences: true},
+	sqldb.PostgreSQL: {integer: "INTEGER", varchar: "VARCHAR", sequences: true},
+	sqldb.SQLite3: {integer: "INTEGER", varchar: "VARCHAR", unraised: []error{
+		sqlcommons.ValueTooLargeForColumn, sqlcommons.ValueLargerThanPrecision, sqlcommons.InvalidNumericValue,
+		sqlcommons.SubqueryReturnsMoreThanOneRow,
+	}},
+}
+
+func (d dialect) raises(err error) bool {
+	for _, unraised := range d.unraised {
+		if unraised == err {
+			return false
+		}
+	}
+	return true
+}
+
+type conformance struct {
+	proxy   *sqldb.SQLProxy
+	db      *sql.DB
+	engine  sqldb.DBEngine
+	dialect dialect
+}
+
+// RunConformance checks that the connector and the adapter behave as the built-in ones: the DDL and CRUD statements,
+// the mapping of each portable error, the sequences, the transactions, the translation of the statements (written
+// with :n placeholders, as the adapters expect them) and the handling of NULLs and times. It creates and drops the
+// conformance_groups, conformance_items and conformance_scratch tables, and the conformance_seq sequence. The engines
+// unknown to sqldb are tested with the PostgreSQL DDL.
+func RunConformance(t *testing.T, sqlConnector sqlcommons.SQLConnector, sqlAdapter sqlcommons.SQLAdapter) {
+
+	sqlProxy := sqldb.NewSQLProxyBuilder(sqlConnector).
+		WithAdapter(sqlAdapter).
+		WithLogger(logger.NewNoLogLogger()).
+		Build()
+	db, err := sqlProxy.Open()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlProxy.Close() })
+
+	c := &conformance{proxy: sqlProxy, db: db, engine: sqlProxy.Engine()}
+	var ok bool
+	if c.dialect, ok = dialects[c.engine]; !ok {
+		c.dialect = dialects[sqldb.PostgreSQL]
+	}
+	if c.engine == sqldb.SQLite3 {
+		// Every connection to an in-memory DB has a DB of its own, and enforces the foreign keys on demand
+		db.SetMaxOpenConns(1)
+		_, err := db.Exec("PRAGMA foreign_keys = ON")
+		require.NoError(t, err)
+	}
+
+	c.drop()
+	t.Cleanup(c.drop)
+	c.create(t)
+
+	t.Run("DDL", c.ddl)
+	t.Run("CRUD", c.crud)
+	t.Run("Errors", c.errorMapping)
+	t.Run("Sequences", c.sequences)
+	t.Run("Transactions", c.transactions)
+	t.Run("Translation", c.translation)
+	t.Run("NullsAndTimes", c.nullsAndTimes)
+}
+
+func (c *conformance) create(t *testing.T) {
+	c.exec(t, fmt.Sprintf(`CREATE TABLE conformance_groups (
+		id %s PRIMARY KEY,
+		name %s(10) NOT NULL)`, c.dialect.integer, c.dialect.varchar))
+	c.exec(t, fmt.Sprintf(`CREATE TABLE conformance_items (
+		id %[1]s PRIMARY KEY,
+		name %[2]s(10) NOT NULL,
+		amount NUMERIC(5,2) NULL,
+		quantity %[1]s NULL,
+		created TIMESTAMP NULL,
+		group_id %[1]s NOT NULL REFERENCES conformance_groups (id),
+		CONSTRAINT conformance_items_un UNIQUE (name))`, c.dialect.integer, c.dialect.varchar))
+}
+
+// drop removes whatever a previous run could have left behind.
+func (c *conformance) drop() {
+	for _, statement := range []string{"DROP TABLE conformance_scratch", "DROP TABLE conformance_items", "DROP TABLE conformance_groups"} {
+		c.db.Exec(statement)
+	}
+	if c.dialect.sequences {
+		c.db.Exec("DROP SEQUENCE conformance_seq")
+	}
+}
+
+// reset leaves a single group, and the given items in it.
+func (c *conformance) reset(t *testing.T, names ...string) {
+	c.exec(t, "DELETE FROM conformance_items")
+	c.exec(t, "DELETE FROM conformance_groups")
+	c.exec(t, "INSERT INTO conformance_groups (id, name) VALUES (:1, :2)", 1, "General")
+	for i, name := range names {
+		c.exec(t, "INSERT INTO conformance_items (id, name, quantity, group_id) VALUES (:1, :2, :3, :4)", i+1, name, 10*(i+1), 1)
+	}
+}
+
+func (c *conformance) exec(t *testing.T, query string, args ...interface{}) sql.Result {
+	t.Helper()
+	result, err := c.db.Exec(query, args...)
+	require.NoError(t, err, query)
+	return result
+}
+
+func (c *conformance) count(t *testing.T) int64 {
+	t.Helper()
+	var count int64
+	require.NoError(t, c.db.QueryRow("SELECT count(*) FROM conformance_items").Scan(&count))
+	return count
+}
+
+func (c *conformance) ddl(t *testing.T) {
+	c.exec(t, fmt.Sprintf("CREATE TABLE conformance_scratch (id %s PRIMARY KEY)", c.dialect.integer))
+	c.exec(t, fmt.Sprintf("ALTER TABLE conformance_scratch ADD note %s(10) NULL", c.dialect.varchar))
+	c.exec(t, "INSERT INTO conformance_scratch (id, note) VALUES (:1, :2)", 1, "Note")
+
+	var note string
+	require.NoError(t, c.db.QueryRow("SELECT note FROM conformance_scratch WHERE id = :1", 1).Scan(&note))
+	require.Equal(t, "Note", note)
+
+	c.exec(t, "DROP TABLE conformance_scratch")
+	_, err := c.db.Exec("SELECT note FROM conformance_scratch")
+	require.Error(t, err)
+}
+
+func (c *conformance) crud(t *testing.T) {
+	c.reset(t)
+	ctx := context.Background()
+
+	result := c.exec(t, "INSERT INTO conformance_items (id, name, quantity, group_id) VALUES (:1, :2, :3, :4)", 1, "Juan", 10, 1)
+	rows, err := result.RowsAffected()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+	c.exec(t, "INSERT INTO conformance_items (id, name, quantity, group_id) VALUES (:1, :2, :3, :4)", 2, "Pedro", 20, 1)
+
+	names, err := sqldb.Select[string](ctx, c.db, "SELECT name FROM conformance_items ORDER BY id")
+	require.NoError(t, err)
+	require.Equal(t, []string{"Juan", "Pedro"}, names)
+
+	result = c.exec(t, "UPDATE conformance_items SET quantity = quantity + :1 WHERE group_id = :2", 5, 1)
+	rows, err = result.RowsAffected()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), rows)
+
+	var total int64
+	require.NoError(t, c.db.QueryRow("SELECT sum(quantity) FROM conformance_items").Scan(&total))
+	require.Equal(t, int64(40), total)
+
+	result = c.exec(t, "DELETE FROM conformance_items WHERE name = :1", "Juan")
+	rows, err = result.RowsAffected()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+	require.Equal(t, int64(1), c.count(t))
+}
+
+func (c *conformance) errorMapping(t *testing.T) {
+
+	cases := []struct {
+		name  string
+		err   error
+		query string
+		args  []interface{}
+	}{
+		{"UniqueConstraintViolation", sqlcommons.UniqueConstraintViolation,
+			"INSERT INTO conformance_items (id, name, group_id) VALUES (:1, :2, :3)", []interface{}{3, "Juan", 1}},
+		{"IntegrityConstraintViolationOnInsert", sqlcommons.IntegrityConstraintViolation,
+			"INSERT INTO conformance_items (id, name, group_id) VALUES (:1, :2, :3)", []interface{}{3, "Pablo", 2}},
+		{"IntegrityConstraintViolationOnDelete", sqlcommons.IntegrityConstraintViolation,
+			"DELETE FROM conformance_groups WHERE id = :1", []interface{}{1}},
+		{"CannotSetNullColumnOnInsert", sqlcommons.CannotSetNullColumn,
+			"INSERT INTO conformance_items (id, name, group_id) VALUES (:1, :2, :3)", []interface{}{3, nil, 1}},
+		{"CannotSetNullColumnOnUpdate", sqlcommons.CannotSetNullColumn,
+			"UPDATE conformance_items SET name = :1 WHERE id = :2", []interface{}{nil, 1}},
+		{"ValueTooLargeForColumn", sqlcommons.ValueTooLargeForColumn,
+			"UPDATE conformance_items SET name = :1 WHERE id = :2", []interface{}{"Juan Carlos Pedro", 1}},
+		{"ValueLargerThanPrecision", sqlcommons.ValueLargerThanPrecision,
+			"UPDATE conformance_items SET amount = 12345.678 WHERE id = :1", []interface{}{1}},
+		{"InvalidNumericValue", sqlcommons.InvalidNumericValue,
+			"UPDATE conformance_items SET quantity = 'twelve' WHERE id = :1", []interface{}{1}},
+		{"SubqueryReturnsMoreThanOneRow", sqlcommons.SubqueryReturnsMoreThanOneRow,
+			"UPDATE conformance_items SET quantity = (SELECT quantity FROM conformance_items) WHERE id = :1", []interface{}{1}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if !c.dialect.raises(tc.err) {
+				t.Skipf("%s never raises %v", c.engine, tc.err)
+			}
+			c.reset(t, "Juan", "Pedro")
+
+			_, err := c.db.Exec(tc.query, tc.args...)
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func (c *conformance) sequences(t *testing.T) {
+	if !c.dialect.sequences {
+		t.Skipf("%s has no sequences", c.engine)
+	}
+	ctx := context.Background()
+	c.exec(t, "CREATE SEQUENCE conformance_seq")
+
+	first, err := c.proxy.GetNextSequenceValue(ctx, "conformance_seq")
+	require.NoError(t, err)
+	second, err := c.proxy.GetNextSequenceValue(ctx, "conformance_seq")
+	require.NoError(t, err)
+	require.Greater(t, second, first)
+
+	_, err = c.proxy.GetNextSequenceValue(ctx, "conformance_missing_seq")
+	require.ErrorIs(t, err, sqlcommons.NextValueFailed)
+}
+
+func (c *conformance) transactions(t *testing.T) {
+	c.reset(t)
+	ctx := context.Background()
+	insert := "INSERT INTO conformance_items (id, name, group_id) VALUES (:1, :2, :3)"
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	_, err = tx.ExecContext(ctx, insert, 1, "Juan", 1)
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	tx, err = c.db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	_, err = tx.ExecContext(ctx, insert, 2, "Pedro", 1)
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+	require.Equal(t, int64(1), c.count(t))
+
+	tx, err = c.proxy.BeginReadOnly(ctx)
+	require.NoError(t, err)
+	_, err = tx.ExecContext(ctx, insert, 3, "Pablo", 1)
+	require.Error(t, err)
+	require.NoError(t, tx.Rollback())
+	require.Equal(t, int64(1), c.count(t))
+}
+
+func (c *conformance) translation(t *testing.T) {
+	c.reset(t, "Juan", "Pedro")
+
+	var count int64
+	require.NoError(t, c.db.QueryRow("SELECT count(*) FROM conformance_items WHERE id IN (:1, :2, :3, :4, :5, :6, :7, :8, :9, :10)",
+		10, 9, 8, 7, 6, 5, 4, 3, 2, 1).Scan(&count))
+	require.Equal(t, int64(2), count)
+
+	var name string
+	var quantity int64
+	require.NoError(t, c.db.QueryRow("SELECT name, quantity FROM conformance_items WHERE name = :1 AND quantity > :2",
+		"Pedro", 10).Scan(&name, &quantity))
+	require.Equal(t, "Pedro", name)
+	require.Equal(t, int64(20), quantity)
+
+	err := c.db.QueryRow("SELECT name FROM conformance_items WHERE name = :1", "Pablo").Scan(&name)
+	require.True(t, errors.Is(err, sql.ErrNoRows))
+}
+
+func (c *conformance) nullsAndTimes(t *testing.T) {
+	c.reset(t)
+	created := time.Date(2024, 2, 29, 13, 45, 30, 0, time.UTC)
+	insert := "INSERT INTO conformance_items (id, name, amount, quantity, created, group_id) VALUES (:1, :2, :3, :4, :5, :6)"
+	c.exec(t, insert, 1, "Juan", 12.5, nil, created, 1)
+	c.exec(t, insert, 2, "Pedro", nil, 3, nil, 1)
+
+	var amount sql.NullFloat64
+	var quantity sql.NullInt64
+	var createdAt sql.NullTime
+	query := "SELECT amount, quantity, created FROM conformance_items WHERE id = :1"
+
+	require.NoError(t, c.db.QueryRow(query, 1).Scan(&amount, &quantity, &createdAt))
+	require.Equal(t, sql.NullFloat64{Float64: 12.5, Valid: true}, amount)
+	require.False(t, quantity.Valid)
+	require.True(t, createdAt.Valid)
+	// Whatever the time zone the driver reads a TIMESTAMP in, the wall clock must be kept
+	require.Equal(t, created.Format(time.DateTime), createdAt.Time.Format(time.DateTime))
+
+	require.NoError(t, c.db.QueryRow(query, 2).Scan(&amount, &quantity, &createdAt))
+	require.False(t, amount.Valid)
+	require.Equal(t, sql.NullInt64{Int64: 3, Valid: true}, quantity)
+	require.False(t, createdAt.Valid)
+
+	names, err := sqldb.Select[string](context.Background(), c.db, "SELECT name FROM conformance_items WHERE quantity IS NULL")
+	require.NoError(t, err)
+	require.Equal(t, []string{"Juan"}, names)
+}
@@ -0,0 +1,194 @@
+package sqldbtest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Execer is implemented by sql.DB, sql.Conn and sql.Tx.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// LoadFixtures runs the statements of the SQL files (separated by semicolons, so PL/SQL blocks aren't supported, but
+// the PostgreSQL functions and triggers whose body is dollar-quoted are) and
+// inserts the rows of the YAML files, which map each table to its rows, in the order they are written:
+//
+//	customers_groups:
+//	  - id: 1
+//	    groupname: General
+//
+// The statements go through the adapter of the DB, so they are written with :n placeholders.
+func LoadFixtures(ctx context.Context, db Execer, paths ...string) error {
+	for _, path := range paths {
+		var err error
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".sql":
+			err = loadSQL(ctx, db, path)
+		case ".yaml", ".yml":
+			err = loadYAML(ctx, db, path)
+		default:
+			err = errors.New("unsupported fixture file")
+		}
+		if err != nil {
+			return fmt.Errorf("loading %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func loadSQL(ctx context.Context, db Execer, path string) error {
+	script, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	for _, statement := range splitStatements(string(script)) {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("%s: %w", statement, err)
+		}
+	}
+	return nil
+}
+
+// splitStatements splits the script on the semicolons outside literals (Oracle q'[...]' and PostgreSQL $$...$$ ones
+// too) and comments, leaving out the statements made of comments only.
+func splitStatements(script string) []string {
+
+	var statements []string
+	start, hasCode := 0, false
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case strings.HasPrefix(script[i:], "--"):
+			i = skipPast(script, i+2, "\n")
+		case strings.HasPrefix(script[i:], "/*"):
+			i = skipPast(script, i+2, "*/")
+		case isOracleQuote(script, i):
+			from := i + strings.IndexByte(script[i:], '\'') + 1
+			i, hasCode = skipPast(script, from+1, string(closingDelimiter(script[from]))+"'"), true
+		case c == '$' && dollarTag(script, i) != "":
+			tag := dollarTag(script, i)
+			i, hasCode = skipPast(script, i+len(tag), tag), true
+		case c == '\'' || c == '"':
+			i, hasCode = skipPast(script, i+1, string(c)), true
+		case c == ';':
+			if hasCode {
+				statements = append(statements, strings.TrimSpace(script[start:i]))
+			}
+			start, hasCode = i+1, false
+		case c != ' ' && c != '\t' && c != '\r' && c != '\n':
+			hasCode = true
+		}
+	}
+	if hasCode {
+		statements = append(statements, strings.TrimSpace(script[start:]))
+	}
+	return statements
+}
+
+// isOracleQuote tells whether an Oracle alternative quoting literal (q'[...]', nq'{...}') starts at i.
+func isOracleQuote(script string, i int) bool {
+	if i > 0 && isWordChar(script[i-1]) {
+		return false
+	}
+	rest := strings.ToLower(script[i:min(i+4, len(script))])
+	return strings.HasPrefix(rest, "q'") && len(rest) > 2 || strings.HasPrefix(rest, "nq'") && len(rest) > 3
+}
+
+func closingDelimiter(delimiter byte) byte {
+	switch delimiter {
+	case '[':
+		return ']'
+	case '{':
+		return '}'
+	case '(':
+		return ')'
+	case '<':
+		return '>'
+	default:
+		return delimiter
+	}
+}
+
+// dollarTag returns the tag ($$ or $name$) of the PostgreSQL dollar-quoted string starting at i, if any.
+func dollarTag(script string, i int) string {
+	if i > 0 && isWordChar(script[i-1]) {
+		return ""
+	}
+	end := i + 1
+	if end < len(script) && (script[end] < '0' || script[end] > '9') {
+		for end < len(script) && script[end] != '$' && isWordChar(script[end]) {
+			end++
+		}
+	}
+	if end >= len(script) || script[end] != '$' {
+		return ""
+	}
+	return script[i : end+1]
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+// skipPast returns the index of the last byte of the terminator, or the end of the script.
+func skipPast(script string, from int, terminator string) int {
+	if idx := strings.Index(script[from:], terminator); idx >= 0 {
+		return from + idx + len(terminator) - 1
+	}
+	return len(script)
+}
+
+func loadYAML(ctx context.Context, db Execer, path string) error {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return err
+	}
+	if len(document.Content) == 0 {
+		return nil
+	}
+	tables := document.Content[0]
+	if tables.Kind != yaml.MappingNode {
+		return errors.New("the fixture must map each table to its rows")
+	}
+
+	for i := 0; i < len(tables.Content); i += 2 {
+		table, rows := tables.Content[i].Value, tables.Content[i+1]
+		if rows.Kind != yaml.SequenceNode {
+			return fmt.Errorf("the rows of %s must be a list", table)
+		}
+		for _, row := range rows.Content {
+			if row.Kind != yaml.MappingNode {
+				return fmt.Errorf("the rows of %s must map each column to its value", table)
+			}
+			var columns, placeholders []string
+			var args []interface{}
+			for j := 0; j < len(row.Content); j += 2 {
+				var value interface{}
+				if err := row.Content[j+1].Decode(&value); err != nil {
+					return err
+				}
+				columns = append(columns, row.Content[j].Value)
+				placeholders = append(placeholders, fmt.Sprintf(":%d", len(columns)))
+				args = append(args, value)
+			}
+			query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+			if _, err := db.ExecContext(ctx, query, args...); err != nil {
+				return fmt.Errorf("inserting into %s: %w", table, err)
+			}
+		}
+	}
+	return nil
+}
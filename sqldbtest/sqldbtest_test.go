@@ -0,0 +1,126 @@
+package sqldbtest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cdleo/go-commons/sqlcommons"
+	sqldb "github.com/cdleo/go-sqldb"
+	"github.com/cdleo/go-sqldb/adapter"
+	"github.com/cdleo/go-sqldb/connector"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Conformance_SQLite3(t *testing.T) {
+	RunConformance(t, connector.NewSqlite3Connector(":memory:"), adapter.NewSQLite3Adapter())
+}
+
+func Test_Conformance_PostgreSQL(t *testing.T) {
+	sqlConnector, sqlAdapter := ConnectorFromEnv(t, PostgresDSNEnv)
+	RunConformance(t, sqlConnector, sqlAdapter)
+}
+
+func Test_Conformance_Oracle(t *testing.T) {
+	sqlConnector, sqlAdapter := ConnectorFromEnv(t, OracleDSNEnv)
+	RunConformance(t, sqlConnector, sqlAdapter)
+}
+
+func Test_TestDB_LoadsFixtures(t *testing.T) {
+	engines := map[sqldb.DBEngine]string{sqldb.SQLite3: "", sqldb.PostgreSQL: PostgresDSNEnv, sqldb.Oracle: OracleDSNEnv}
+	for engine, env := range engines {
+		t.Run(string(engine), func(t *testing.T) {
+			// Setup
+			if env != "" && os.Getenv(env) == "" {
+				t.Skipf("%s not set", env)
+			}
+			testDB := NewTestDB(t).
+				WithEngineFromEnv(env).
+				WithFixtures("testdata/schema.sql", "testdata/customers.yaml").
+				Build()
+			ctx := context.Background()
+
+			// Exec
+			groups, err := sqldb.Select[string](ctx, testDB.DB, "SELECT groupname FROM customers_groups ORDER BY id")
+			require.NoError(t, err)
+			require.Equal(t, []string{"General; retail", "Premium"}, groups)
+
+			var updatetime sql.NullTime
+			var age sql.NullInt64
+			require.NoError(t, testDB.DB.QueryRow("SELECT updatetime, age FROM customers WHERE name = :1", "Juan").Scan(&updatetime, &age))
+			require.Equal(t, "2024-02-29 13:45:30", updatetime.Time.Format(time.DateTime))
+			require.False(t, age.Valid)
+
+			_, err = testDB.DB.Exec("INSERT INTO customers (id, name, cust_group) VALUES (:1, :2, :3)", 3, "Pablo", 3)
+			require.ErrorIs(t, err, sqlcommons.IntegrityConstraintViolation)
+		})
+	}
+}
+
+func Test_TestDB_IsolatesEachTest(t *testing.T) {
+	// Setup
+	first := NewTestDB(t).WithFixtures("testdata/schema.sql").Build()
+	second := NewTestDB(t).OnFile().WithFixtures("testdata/schema.sql").Build()
+
+	// Exec
+	_, err := first.DB.Exec("INSERT INTO customers (id, name, cust_group) VALUES (:1, :2, :3)", 1, "Juan", 1)
+	require.NoError(t, err)
+	_, err = second.DB.Exec("INSERT INTO customers (id, name, cust_group) VALUES (:1, :2, :3)", 1, "Juan", 1)
+	require.NoError(t, err)
+
+	_, err = first.DB.Exec("DROP TABLE customers")
+	require.NoError(t, err)
+	var count int
+	require.NoError(t, second.DB.QueryRow("SELECT count(*) FROM customers").Scan(&count))
+	require.Equal(t, 1, count)
+}
+
+func Test_TestDB_InTransaction(t *testing.T) {
+	for _, onFile := range []bool{false, true} {
+		// Setup
+		var testDB *TestDB
+
+		// Exec
+		t.Run(fmt.Sprintf("OnFile=%t", onFile), func(t *testing.T) {
+			builder := NewTestDB(t).WithFixtures("testdata/schema.sql").InTransaction()
+			if onFile {
+				builder.OnFile()
+			}
+			testDB = builder.Build()
+
+			_, err := testDB.Tx.Exec("INSERT INTO customers (id, name, cust_group) VALUES (:1, :2, :3)", 1, "Juan", 1)
+			require.NoError(t, err)
+			var count int
+			require.NoError(t, testDB.Tx.QueryRow("SELECT count(*) FROM customers").Scan(&count))
+			require.Equal(t, 1, count)
+
+			// The DB is still usable, outside the transaction
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			require.NoError(t, testDB.DB.QueryRowContext(ctx, "SELECT count(*) FROM customers_groups").Scan(&count))
+			require.Equal(t, 1, count)
+		})
+
+		require.ErrorIs(t, testDB.Tx.Commit(), sql.ErrTxDone)
+	}
+}
+
+func Test_splitStatements(t *testing.T) {
+	statements := splitStatements(`-- Leading comment
+		SELECT 'a;b' FROM dual; /* ; */ SELECT "c;d" FROM dual
+		; -- trailing comment;`)
+
+	require.Equal(t, []string{"-- Leading comment\n\t\tSELECT 'a;b' FROM dual", `/* ; */ SELECT "c;d" FROM dual`}, statements)
+	statements = splitStatements(`CREATE FUNCTION touch() RETURNS trigger AS $$
+		BEGIN NEW.updated = now(); RETURN NEW; END;
+		$$ LANGUAGE plpgsql;
+		SELECT $tag$a;b$tag$, q'[it's;]', Nq'{;}' FROM dual;`)
+
+	require.Len(t, statements, 2)
+	require.True(t, strings.HasSuffix(statements[0], "$$ LANGUAGE plpgsql"))
+	require.Equal(t, "SELECT $tag$a;b$tag$, q'[it's;]', Nq'{;}' FROM dual", statements[1])
+}
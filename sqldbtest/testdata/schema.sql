@@ -0,0 +1,15 @@
+-- Customers and their groups; each statement ends with a semicolon
+CREATE TABLE customers_groups (
+	id INTEGER PRIMARY KEY,
+	groupname VARCHAR(20) NOT NULL);
+
+CREATE TABLE customers (
+	id INTEGER PRIMARY KEY,
+	name VARCHAR(10) NOT NULL,
+	updatetime TIMESTAMP NULL,
+	age INTEGER NULL,
+	cust_group INTEGER NOT NULL REFERENCES customers_groups (id),
+	CONSTRAINT customers_un UNIQUE (name));
+
+/* The groups known beforehand; the literal holds a semicolon */
+INSERT INTO customers_groups (id, groupname) VALUES (1, 'General; retail');
@@ -0,0 +1,159 @@
+package sqldbtest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cdleo/go-commons/logger"
+	"github.com/cdleo/go-commons/sqlcommons"
+	sqldb "github.com/cdleo/go-sqldb"
+	"github.com/cdleo/go-sqldb/adapter"
+	"github.com/cdleo/go-sqldb/connector"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDB is the database of a single test, dropped when the test ends.
+type TestDB struct {
+	Proxy *sqldb.SQLProxy
+	DB    *sql.DB
+	// Tx is the transaction wrapping the test, if asked for
+	Tx *sql.Tx
+}
+
+// TestDBBuilder sets up a database of its own for a test: an SQLite3 one by default, or a schema (on PostgreSQL)
+// or a user (on Oracle) created for the test when a DSN is configured, named after the sqldbtest_ prefix.
+type TestDBBuilder struct {
+	t        testing.TB
+	env      string
+	onFile   bool
+	fixtures []string
+	inTx     bool
+}
+
+func NewTestDB(t testing.TB) *TestDBBuilder {
+	return &TestDBBuilder{t: t}
+}
+
+// WithEngineFromEnv isolates the test on the engine of the DSN held by the env variable (see ConnectorFromEnv),
+// if it's set. The DSN's user must be allowed to create (and drop) schemas on PostgreSQL, and users on Oracle.
+func (b *TestDBBuilder) WithEngineFromEnv(env string) *TestDBBuilder {
+	b.env = env
+	return b
+}
+
+// OnFile keeps the SQLite3 DB on a temporary file rather than in memory.
+func (b *TestDBBuilder) OnFile() *TestDBBuilder {
+	b.onFile = true
+	return b
+}
+
+// WithFixtures loads the SQL and YAML files (see LoadFixtures) once the DB is created.
+func (b *TestDBBuilder) WithFixtures(paths ...string) *TestDBBuilder {
+	b.fixtures = append(b.fixtures, paths...)
+	return b
+}
+
+// InTransaction wraps the test in a transaction (started once the fixtures are loaded), rolled back when it ends.
+// The test must then run its statements on TestDB.Tx: the ones run on TestDB.DB (or Proxy) take other connections,
+// outside the transaction, which don't see its changes (and may wait for or fail on the rows or tables it wrote).
+func (b *TestDBBuilder) InTransaction() *TestDBBuilder {
+	b.inTx = true
+	return b
+}
+
+func (b *TestDBBuilder) Build() *TestDB {
+	b.t.Helper()
+
+	var sqlConnector sqlcommons.SQLConnector
+	var sqlAdapter sqlcommons.SQLAdapter
+	if dsn := b.dsn(); dsn != "" {
+		config, err := parseDSN(dsn)
+		require.NoError(b.t, err, "invalid %s", b.env)
+		sqlConnector, sqlAdapter = b.isolate(config)
+	} else if b.onFile {
+		sqlConnector, sqlAdapter = connector.NewSqlite3Connector(filepath.Join(b.t.TempDir(), "test.db")+"?_foreign_keys=on"), adapter.NewSQLite3Adapter()
+	} else {
+		// A named in-memory DB, shared between the connections of the pool (cache=shared)
+		sqlConnector, sqlAdapter = connector.NewSqlite3Connector(fmt.Sprintf("file:sqldbtest_%08x?mode=memory&cache=shared&_foreign_keys=on", rand.Uint32())), adapter.NewSQLite3Adapter()
+	}
+
+	sqlProxy := sqldb.NewSQLProxyBuilder(sqlConnector).
+		WithAdapter(sqlAdapter).
+		WithLogger(logger.NewNoLogLogger()).
+		Build()
+	db, err := sqlProxy.Open()
+	require.NoError(b.t, err)
+	b.t.Cleanup(func() { sqlProxy.Close() })
+
+	ctx := context.Background()
+	if b.dsn() == "" && !b.onFile {
+		// The in-memory DB lives as long as one of its connections, so one is held until the test ends
+		keeper, err := db.Conn(ctx)
+		require.NoError(b.t, err)
+		b.t.Cleanup(func() { keeper.Close() })
+	}
+	require.NoError(b.t, LoadFixtures(ctx, db, b.fixtures...))
+
+	testDB := &TestDB{Proxy: sqlProxy, DB: db}
+	if b.inTx {
+		testDB.Tx, err = db.BeginTx(ctx, nil)
+		require.NoError(b.t, err)
+		b.t.Cleanup(func() { testDB.Tx.Rollback() })
+	}
+	return testDB
+}
+
+func (b *TestDBBuilder) dsn() string {
+	if b.env == "" {
+		return ""
+	}
+	return os.Getenv(b.env)
+}
+
+// isolate creates the schema (or the user) of the test, dropping it once the test ends.
+func (b *TestDBBuilder) isolate(config dsnConfig) (sqlcommons.SQLConnector, sqlcommons.SQLAdapter) {
+
+	admin := sqldb.NewSQLProxyBuilder(config.connector()).
+		WithAdapter(config.adapter()).
+		WithLogger(logger.NewNoLogLogger()).
+		Build()
+	adminDB, err := admin.Open()
+	require.NoError(b.t, err)
+
+	name := fmt.Sprintf("sqldbtest_%08x", rand.Uint32())
+	var create []string
+	var drop string
+	if config.engine == sqldb.Oracle {
+		password := fmt.Sprintf("P%016x", rand.Uint64())
+		create = []string{
+			fmt.Sprintf(`CREATE USER %s IDENTIFIED BY "%s"`, name, password),
+			fmt.Sprintf("GRANT CREATE SESSION, CREATE TABLE, CREATE SEQUENCE, CREATE VIEW, CREATE PROCEDURE, CREATE TRIGGER, UNLIMITED TABLESPACE TO %s", name),
+		}
+		drop = fmt.Sprintf("DROP USER %s CASCADE", name)
+		config.user, config.password = name, password
+	} else {
+		create = []string{"CREATE SCHEMA " + name}
+		drop = fmt.Sprintf("DROP SCHEMA %s CASCADE", name)
+	}
+
+	// Registered first, so it runs once the test's connections are closed
+	b.t.Cleanup(func() {
+		adminDB.Exec(drop)
+		admin.Close()
+	})
+	for _, statement := range create {
+		_, err := adminDB.Exec(statement)
+		require.NoError(b.t, err)
+	}
+
+	sqlConnector := config.connector()
+	if schemaSetter, ok := sqlConnector.(interface{ WithSchema(schema string) }); ok {
+		schemaSetter.WithSchema(name)
+	}
+	return sqlConnector, config.adapter()
+}
@@ -0,0 +1,35 @@
+/*
+Package sqlerrors complements the portable error set of sqlcommons with the errors raised by the proxy itself.
+*/
+package sqlerrors
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors
+var (
+	StatementNotAllowed = errors.New("Statement not allowed by policy")
+	QueryTimeout        = errors.New("Query timeout")
+	CircuitOpen         = errors.New("Circuit breaker open")
+	AdmissionRejected   = errors.New("Query rejected by admission control")
+	UnmappedColumn      = errors.New("Column not mapped to a field")
+	InvalidPageToken    = errors.New("Invalid page token")
+	UnexpectedStatement = errors.New("Statement not found in the fixture")
+	InjectedFault       = errors.New("Injected fault")
+)
+
+// BulkInsertError reports the row (by its index) whose insertion made a bulk insert fail.
+type BulkInsertError struct {
+	Row int
+	Err error
+}
+
+func (e *BulkInsertError) Error() string {
+	return fmt.Sprintf("Bulk insert failed at row %d: %v", e.Row, e.Err)
+}
+
+func (e *BulkInsertError) Unwrap() error {
+	return e.Err
+}